@@ -0,0 +1,165 @@
+package log
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kyfk/log/level"
+)
+
+// OverflowPolicy decides what happens to a message
+// when the buffer of an asynchronous logger is full.
+type OverflowPolicy struct {
+	kind  overflowKind
+	level level.Level
+}
+
+type overflowKind int
+
+const (
+	overflowBlock overflowKind = iota
+	overflowDropNewest
+	overflowDropOldest
+	overflowDropBelow
+)
+
+var (
+	// Block makes the logging call wait until the buffer has room.
+	Block = OverflowPolicy{kind: overflowBlock}
+	// DropNewest drops the message that is going to be buffered.
+	DropNewest = OverflowPolicy{kind: overflowDropNewest}
+	// DropOldest drops the oldest message in the buffer to make room.
+	DropOldest = OverflowPolicy{kind: overflowDropOldest}
+)
+
+// DropBelow returns OverflowPolicy that drops the message if its level is less than lv,
+// otherwise the logging call waits until the buffer has room.
+func DropBelow(lv level.Level) OverflowPolicy {
+	return OverflowPolicy{kind: overflowDropBelow, level: lv}
+}
+
+// record is a formatted message waiting to be written.
+type record struct {
+	out   *log.Logger
+	level level.Level
+	line  string
+}
+
+func (r record) write() {
+	r.out.Output(2, r.line)
+}
+
+// asyncWriter buffers records into a ring buffer
+// and writes them from a background goroutine.
+type asyncWriter struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     []record
+	head    int
+	n       int
+	writing bool
+	closed  bool
+	policy  OverflowPolicy
+	dropped uint64
+	done    chan struct{}
+}
+
+func newAsyncWriter(size int, policy OverflowPolicy) *asyncWriter {
+	if size < 1 {
+		size = 1
+	}
+	w := &asyncWriter{
+		buf:    make([]record, size),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// enqueue buffers the record along the overflow policy.
+// After the writer is closed, the record is written synchronously.
+func (w *asyncWriter) enqueue(r record) {
+	w.mu.Lock()
+	for !w.closed && w.n == len(w.buf) {
+		switch w.policy.kind {
+		case overflowDropNewest:
+			w.mu.Unlock()
+			atomic.AddUint64(&w.dropped, 1)
+			return
+		case overflowDropOldest:
+			w.buf[w.head] = record{}
+			w.head = (w.head + 1) % len(w.buf)
+			w.n--
+			atomic.AddUint64(&w.dropped, 1)
+		case overflowDropBelow:
+			if r.level.LessThan(w.policy.level) {
+				w.mu.Unlock()
+				atomic.AddUint64(&w.dropped, 1)
+				return
+			}
+			w.cond.Wait()
+		default:
+			w.cond.Wait()
+		}
+	}
+	if w.closed {
+		w.mu.Unlock()
+		r.write()
+		return
+	}
+
+	w.buf[(w.head+w.n)%len(w.buf)] = r
+	w.n++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+
+	w.mu.Lock()
+	for {
+		for w.n == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.n == 0 {
+			w.mu.Unlock()
+			return
+		}
+
+		r := w.buf[w.head]
+		w.buf[w.head] = record{}
+		w.head = (w.head + 1) % len(w.buf)
+		w.n--
+		w.writing = true
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		r.write()
+
+		w.mu.Lock()
+		w.writing = false
+		w.cond.Broadcast()
+	}
+}
+
+// flush waits until all the buffered records are written.
+func (w *asyncWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.n > 0 || w.writing {
+		w.cond.Wait()
+	}
+}
+
+// close writes all the buffered records and stops the background goroutine.
+func (w *asyncWriter) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+// gatedWriter blocks writing until it is opened.
+type gatedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	gate    chan struct{}
+	once    sync.Once
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func messageFormat(v map[string]interface{}) (string, error) {
+	return v["message"].(string), nil
+}
+
+func TestAsync(t *testing.T) {
+	assert := assert.New(t)
+
+	// newBlockedLogger returns a logger whose first message is being written
+	// and whose buffer of size 1 is filled with the second message.
+	newBlockedLogger := func(policy OverflowPolicy) (*Logger, *gatedWriter) {
+		w := newGatedWriter()
+		logger := New(Output(w), Format(messageFormat), Async(1, policy))
+		logger.Info("1")
+		<-w.started
+		logger.Info("2")
+		return logger, w
+	}
+
+	t.Run("messages are written asynchronously and flushed", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		logger := New(Output(buf), Format(messageFormat), Async(16, Block))
+		for _, m := range []string{"1", "2", "3"} {
+			logger.Info(m)
+		}
+		logger.Flush()
+		assert.Equal("1\n2\n3\n", buf.String())
+		assert.NoError(logger.Close())
+	})
+
+	t.Run("DropNewest drops the message that is going to be buffered", func(t *testing.T) {
+		logger, w := newBlockedLogger(DropNewest)
+		logger.Info("3")
+		close(w.gate)
+		logger.Flush()
+		assert.Equal("1\n2\n", w.String())
+		assert.Equal(uint64(1), logger.Dropped())
+	})
+
+	t.Run("DropOldest drops the oldest message in the buffer", func(t *testing.T) {
+		logger, w := newBlockedLogger(DropOldest)
+		logger.Info("3")
+		close(w.gate)
+		logger.Flush()
+		assert.Equal("1\n3\n", w.String())
+		assert.Equal(uint64(1), logger.Dropped())
+	})
+
+	t.Run("DropBelow drops messages of lower level", func(t *testing.T) {
+		logger, w := newBlockedLogger(DropBelow(level.Warn))
+		logger.Info("3")
+		close(w.gate)
+		logger.Flush()
+		assert.Equal("1\n2\n", w.String())
+		assert.Equal(uint64(1), logger.Dropped())
+	})
+
+	t.Run("Block waits until the buffer has room", func(t *testing.T) {
+		logger, w := newBlockedLogger(Block)
+		done := make(chan struct{})
+		go func() {
+			logger.Info("3")
+			close(done)
+		}()
+		close(w.gate)
+		<-done
+		assert.NoError(logger.Close())
+		assert.Equal("1\n2\n3\n", w.String())
+		assert.Equal(uint64(0), logger.Dropped())
+	})
+
+	t.Run("Panic flushes the buffered messages", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		logger := New(Output(buf), Format(messageFormat), Async(16, Block))
+		logger.Info("1")
+		assert.Panics(func() { logger.Panic("panic") })
+		assert.Panics(func() { logger.Panicf("%s", "panicf") })
+		assert.Equal("1\npanic\npanicf\n", buf.String())
+		assert.NoError(logger.Close())
+	})
+
+	t.Run("messages after Close are written synchronously", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		logger := New(Output(buf), Format(messageFormat), Async(16, Block))
+		assert.NoError(logger.Close())
+		logger.Info("1")
+		assert.Equal("1\n", buf.String())
+	})
+}
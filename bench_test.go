@@ -0,0 +1,87 @@
+package log
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/level"
+)
+
+// The benchmarks compare logging with typed fields through the JSON encoder of JSONLayout
+// against logging with the map of fields through format.JSON, which uses encoding/json.
+
+func newBenchmarkLogger(ops ...Option) *Logger {
+	return New(append([]Option{
+		Output(ioutil.Discard),
+		FormatLayout(JSONLayout),
+		Metadata(map[string]interface{}{
+			"service":    "book",
+			"request_id": "943ad105-7543-11e6-a9ac-65e093327849",
+		}),
+	}, ops...)...)
+}
+
+func BenchmarkInfoFields(b *testing.B) {
+	logger := newBenchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoFields("info",
+			String("user_id", "86f32b8b-ec0d-479f-aed1-1070aa54cecf"),
+			Int("count", i),
+			Duration("elapsed", time.Millisecond),
+			Err(errors.New("error")),
+		)
+	}
+}
+
+func BenchmarkInfow(b *testing.B) {
+	logger := newBenchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Infow("info",
+			"user_id", "86f32b8b-ec0d-479f-aed1-1070aa54cecf",
+			"count", i,
+			"elapsed", time.Millisecond,
+			"error", errors.New("error").Error(),
+		)
+	}
+}
+
+func BenchmarkInfoFieldsDisabled(b *testing.B) {
+	logger := newBenchmarkLogger(MinLevel(level.Warn))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoFields("info",
+			String("user_id", "86f32b8b-ec0d-479f-aed1-1070aa54cecf"),
+			Int("count", i),
+			Duration("elapsed", time.Millisecond),
+		)
+	}
+}
+
+func BenchmarkInfowDisabled(b *testing.B) {
+	logger := newBenchmarkLogger(MinLevel(level.Warn))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Infow("info",
+			"user_id", "86f32b8b-ec0d-479f-aed1-1070aa54cecf",
+			"count", i,
+			"elapsed", time.Millisecond,
+		)
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	logger := newBenchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("info")
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// CallerMode is the mode of the caller field of entries.
+type CallerMode int
+
+const (
+	// CallerOff doesn't add the caller field.
+	CallerOff CallerMode = iota
+	// CallerShort adds the caller field with the file path shortened to
+	// the last directory and the file name like "log/logger.go".
+	CallerShort
+	// CallerFull adds the caller field with the full file path.
+	CallerFull
+)
+
+// libraryDir is the directory of this package.
+// The frames of the files in it are skipped to find the caller of the logger,
+// except for the tests of this package.
+var libraryDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callerFrame is the information of a frame resolved from a program counter.
+type callerFrame struct {
+	frame   runtime.Frame
+	pkg     string // the import path of the package of the function
+	library bool   // whether the frame is in this package
+}
+
+// callerFrames caches *callerFrame per program counter
+// because resolving frames is too slow to do on every logging.
+var callerFrames sync.Map
+
+func frameOf(pc uintptr) *callerFrame {
+	if f, ok := callerFrames.Load(pc); ok {
+		return f.(*callerFrame)
+	}
+
+	fr, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	f := &callerFrame{
+		frame:   fr,
+		pkg:     funcPackage(fr.Function),
+		library: filepath.Dir(fr.File) == libraryDir && !strings.HasSuffix(fr.File, "_test.go"),
+	}
+	callerFrames.Store(pc, f)
+	return f
+}
+
+// callerPC returns the program counter of the caller of the logger,
+// which is the first frame out of this package, skipping skip more frames.
+// It returns 0 if the caller isn't found.
+func callerPC(skip int) uintptr {
+	var buf [16]uintptr
+	pcs := buf[:]
+	if skip > len(buf)/2 {
+		pcs = make([]uintptr, len(buf)+skip)
+	}
+	n := runtime.Callers(2, pcs)
+	for i, pc := range pcs[:n] {
+		if !frameOf(pc).library {
+			if i+skip < n {
+				return pcs[i+skip]
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// callerOf returns the frame of the caller at pc in mode.
+// It returns nil if pc is 0.
+func callerOf(pc uintptr, mode CallerMode) *Frame {
+	if pc == 0 {
+		return nil
+	}
+	f := frameOf(pc).frame
+	file := f.File
+	if mode == CallerShort {
+		file = shortPath(file)
+	}
+	return &Frame{Function: f.Function, File: file, Line: f.Line}
+}
+
+// shortPath returns the last directory and the file name of path.
+func shortPath(path string) string {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return path
+	}
+	if j := strings.LastIndexByte(path[:i], '/'); j >= 0 {
+		return path[j+1:]
+	}
+	return path
+}
+
+// funcPackage returns the import path of the package from the name of function
+// like "github.com/kyfk/log.(*Logger).Info".
+// The dots in the last element of the path are escaped in the name like "gopkg.in/yaml%2ev3.Unmarshal",
+// so they are unescaped.
+func funcPackage(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if slash < 0 {
+		slash = 0
+	}
+	pkg := fn
+	if dot := strings.Index(fn[slash:], "."); dot >= 0 {
+		pkg = fn[:slash+dot]
+	}
+	if strings.IndexByte(pkg, '%') < 0 {
+		return pkg
+	}
+	if p, err := url.PathUnescape(pkg); err == nil {
+		return p
+	}
+	return pkg
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// here returns the frame of its caller in mode.
+func here(mode CallerMode) Frame {
+	pc, file, line, _ := runtime.Caller(1)
+	if mode == CallerShort {
+		file = filepath.Base(filepath.Dir(file)) + "/" + filepath.Base(file)
+	}
+	return Frame{Function: runtime.FuncForPC(pc).Name(), File: file, Line: line}
+}
+
+func callerOfOutput(t *testing.T, buf *bytes.Buffer) Frame {
+	var v struct {
+		Caller Frame `json:"caller"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &v))
+	buf.Reset()
+	return v.Caller
+}
+
+func TestCaller(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		Caller(CallerShort),
+		Format(format.JSON),
+		Output(buf),
+	)
+
+	t.Run("short path", func(t *testing.T) {
+		logger.Info("info")
+		want := here(CallerShort)
+		want.Line--
+		assert.Equal(want, callerOfOutput(t, buf))
+	})
+
+	t.Run("typed fields", func(t *testing.T) {
+		logger.InfoFields("info", String("k", "v"))
+		want := here(CallerShort)
+		want.Line--
+		assert.Equal(want, callerOfOutput(t, buf))
+	})
+
+	t.Run("full path", func(t *testing.T) {
+		logger.SetCaller(CallerFull)
+		defer logger.SetCaller(CallerShort)
+		logger.Info("info")
+		want := here(CallerFull)
+		want.Line--
+		assert.Equal(want, callerOfOutput(t, buf))
+	})
+
+	t.Run("slog", func(t *testing.T) {
+		slog.New(NewSlogHandler(logger)).Info("info")
+		want := here(CallerShort)
+		want.Line--
+		assert.Equal(want, callerOfOutput(t, buf))
+	})
+
+	t.Run("caller skip for wrapper", func(t *testing.T) {
+		logger.SetCallerSkip(1)
+		defer logger.SetCallerSkip(0)
+		wrap := func(msg string) { logger.Info(msg) }
+		wrap("info")
+		want := here(CallerShort)
+		want.Line--
+		assert.Equal(want, callerOfOutput(t, buf))
+	})
+
+	t.Run("off", func(t *testing.T) {
+		logger.SetCaller(CallerOff)
+		logger.Info("info")
+		assert.NotContains(buf.String(), "caller")
+	})
+}
+
+func TestCallerOfDefaultLogger(t *testing.T) {
+	// the default logger is restored because the other tests share it.
+	defer SetMinLevel(defaultLogger.Level())
+	defer SetOutput(os.Stdout)
+	defer SetFormat(format.JSONPretty)
+	defer SetCaller(CallerOff)
+
+	buf := bytes.NewBuffer(nil)
+	SetMinLevel(level.Debug)
+	SetFormat(format.JSON)
+	SetOutput(buf)
+	SetCaller(CallerShort)
+
+	Info("info")
+	want := here(CallerShort)
+	want.Line--
+	assert.Equal(t, want, callerOfOutput(t, buf))
+}
+
+func TestFrameString(t *testing.T) {
+	f := Frame{Function: "main.main", File: "main/main.go", Line: 10}
+	assert.Equal(t, "main.main main/main.go:10", f.String())
+}
+
+func TestShortPath(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("log/logger.go", shortPath("/go/src/github.com/kyfk/log/logger.go"))
+	assert.Equal("log/logger.go", shortPath("log/logger.go"))
+	assert.Equal("logger.go", shortPath("logger.go"))
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kyfk/log/level"
+)
+
+type contextKey struct{}
+
+// NewContext returns a new Context that carries the logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger that ctx carries.
+// If ctx doesn't carry any Logger, the default logger is returned.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return defaultLogger
+}
+
+// ContextExtractor extracts a value to output from a context.
+// The second returned value reports whether the value is found in the context.
+type ContextExtractor func(ctx context.Context) (interface{}, bool)
+
+// ContextValue returns ContextExtractor that extracts the value associated with key.
+func ContextValue(key interface{}) ContextExtractor {
+	return func(ctx context.Context) (interface{}, bool) {
+		v := ctx.Value(key)
+		return v, v != nil
+	}
+}
+
+var contextExtractors = struct {
+	sync.RWMutex
+	m map[string]ContextExtractor
+}{m: map[string]ContextExtractor{}}
+
+// RegisterContextExtractor registers ContextExtractor that extracts the value of field.
+// The XXXContext functions output the extracted values in metadata.
+// For instance, HTTP Request ID, the id of user signed in, tenant and other more.
+// If an extractor is already registered for field, it is replaced.
+func RegisterContextExtractor(field string, ex ContextExtractor) {
+	contextExtractors.Lock()
+	defer contextExtractors.Unlock()
+	contextExtractors.m[field] = ex
+}
+
+// UnregisterContextExtractor removes ContextExtractor registered for field.
+func UnregisterContextExtractor(field string) {
+	contextExtractors.Lock()
+	defer contextExtractors.Unlock()
+	delete(contextExtractors.m, field)
+}
+
+// contextFields returns the values that the registered extractors extract from ctx.
+func contextFields(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	contextExtractors.RLock()
+	defer contextExtractors.RUnlock()
+
+	var fields map[string]interface{}
+	for field, ex := range contextExtractors.m {
+		v, ok := ex(ctx)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		fields[field] = v
+	}
+	return fields
+}
+
+// withContext returns the configuration whose metadata is extended with the values extracted from ctx.
+// It is called after the level is checked so that the disabled levels don't pay for it.
+func (c *config) withContext(ctx context.Context) *config {
+	fields := contextFields(ctx)
+	if len(fields) == 0 {
+		return c
+	}
+	return c.withMetadata(fields)
+}
+
+// DebugContext logs a message at level Debug with the values extracted from ctx.
+func (l *Logger) DebugContext(ctx context.Context, v ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Debug) || len(v) == 0 {
+		return
+	}
+	c = c.withContext(ctx)
+	l.output(c, &Entry{
+		Level:   level.Debug,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
+	})
+}
+
+// InfoContext logs a message at level Info with the values extracted from ctx.
+func (l *Logger) InfoContext(ctx context.Context, v ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Info) || len(v) == 0 {
+		return
+	}
+	c = c.withContext(ctx)
+	l.output(c, &Entry{
+		Level:   level.Info,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
+	})
+}
+
+// WarnContext logs a message at level Warn with the values extracted from ctx.
+func (l *Logger) WarnContext(ctx context.Context, v ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Warn) || len(v) == 0 {
+		return
+	}
+	c = c.withContext(ctx)
+
+	e := &Entry{
+		Level:   level.Warn,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
+	}
+
+	if st, ok := stackTraceOf(v[0]); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// ErrorContext logs a message at level Error with the values extracted from ctx.
+func (l *Logger) ErrorContext(ctx context.Context, err error) {
+	c := l.load()
+	if !c.enabled(level.Error) || err == nil {
+		return
+	}
+	c = c.withContext(ctx)
+
+	e := &Entry{
+		Level: level.Error,
+		Time:  c.nowFunc(),
+		Error: err,
+	}
+
+	if st, ok := stackTraceOf(err); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
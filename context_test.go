@@ -0,0 +1,92 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+type testContextKey string
+
+func TestFromContext(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(defaultLogger, FromContext(context.Background()))
+
+	lg := New()
+	ctx := NewContext(context.Background(), lg)
+	assert.Equal(lg, FromContext(ctx))
+}
+
+func TestContextExtractor(t *testing.T) {
+	assert := assert.New(t)
+
+	RegisterContextExtractor("request_id", ContextValue(testContextKey("request_id")))
+	RegisterContextExtractor("tenant", ContextValue(testContextKey("tenant")))
+	defer UnregisterContextExtractor("request_id")
+	defer UnregisterContextExtractor("tenant")
+
+	buf := bytes.NewBuffer(nil)
+	lg := New(
+		Format(format.JSON),
+		Output(buf),
+		Metadata(map[string]interface{}{"service": "book"}),
+	)
+	lg.update(nowFunc(func() time.Time { return time.Time{} }))
+
+	ctx := context.WithValue(context.Background(), testContextKey("request_id"), "req1")
+
+	t.Run("output values extracted from context", func(t *testing.T) {
+		buf.Reset()
+		lg.InfoContext(ctx, "info")
+		assert.Equal(`{"level":"INFO","message":"info","meta":{"request_id":"req1","service":"book"},"time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("the logger in context is used by package-level functions", func(t *testing.T) {
+		buf.Reset()
+		ErrorContext(NewContext(ctx, lg), errors.New("error"))
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("ERROR", mp["level"])
+		assert.Equal(map[string]interface{}{"request_id": "req1", "service": "book"}, mp["meta"])
+	})
+
+	t.Run("metadata of the logger is not modified", func(t *testing.T) {
+		assert.Equal(map[string]interface{}{"service": "book"}, lg.load().metadata)
+	})
+}
+
+func TestContextDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	var called int
+	RegisterContextExtractor("request_id", func(ctx context.Context) (interface{}, bool) {
+		called++
+		return "req1", true
+	})
+	defer UnregisterContextExtractor("request_id")
+
+	buf := bytes.NewBuffer(nil)
+	lg := New(Output(buf), Format(format.JSON), MinLevel(level.Info))
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		lg.DebugContext(ctx, "debug")
+	})
+	assert.Equal(float64(0), allocs)
+	assert.Equal(0, called)
+	assert.Empty(buf.String())
+
+	lg.InfoContext(ctx, "info")
+	assert.Equal(1, called)
+	assert.Contains(buf.String(), `"meta":{"request_id":"req1"}`)
+	assert.Equal(map[string]interface{}{}, lg.load().metadata)
+}
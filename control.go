@@ -0,0 +1,133 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kyfk/log/level"
+)
+
+// levelRevert holds the state of the temporary minimum logging level set by SetMinLevelFor.
+type levelRevert struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	base  level.Level // the level restored when the timer fires
+	gen   uint64      // distinguishes the current timer from the stopped ones
+}
+
+// SetMinLevelFor sets minumum logging level to a logger temporarily.
+// The level is reverted to the one before the temporary changes after d.
+// If d isn't positive, it is same as SetMinLevel.
+func (l *Logger) SetMinLevelFor(lv level.Level, d time.Duration) {
+	if !lv.Registered() {
+		reportUnregistered(lv)
+		return
+	}
+	if l.root != nil {
+		l.root.SetMinLevelFor(lv, d)
+		return
+	}
+
+	r := &l.revert
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	} else {
+		r.base = l.Level()
+	}
+	r.gen++
+	l.update(MinLevel(lv))
+
+	if d <= 0 {
+		return
+	}
+	gen := r.gen
+	r.timer = time.AfterFunc(d, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// the timer may be stopped after it fired.
+		if r.gen != gen {
+			return
+		}
+		r.timer = nil
+		r.gen++
+		l.update(MinLevel(r.base))
+	})
+}
+
+// levelRequest is the body of PUT request to the handler returned by LevelHandler.
+type levelRequest struct {
+	Level       *level.Level `json:"level"`
+	RevertAfter string       `json:"revert_after"`
+}
+
+// levelResponse is the body of response from the handler returned by LevelHandler.
+type levelResponse struct {
+	Level level.Level `json:"level,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// LevelHandler returns http.Handler that serves the minimum logging level of l as JSON.
+// If l is nil, the default logger is used.
+//
+// GET responds the current level like {"level":"INFO"}.
+// PUT sets the level in the body like {"level":"debug"}. If "revert_after" is also set like
+// {"level":"debug","revert_after":"10m"}, the level is reverted after the duration
+// so that verbose levels aren't left on by mistake.
+func LevelHandler(l *Logger) http.Handler {
+	if l == nil {
+		l = defaultLogger
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelResponse(w, http.StatusBadRequest, levelResponse{Error: err.Error()})
+				return
+			}
+			if req.Level == nil {
+				writeLevelResponse(w, http.StatusBadRequest, levelResponse{Error: "level is required"})
+				return
+			}
+			var d time.Duration
+			if req.RevertAfter != "" {
+				var err error
+				if d, err = time.ParseDuration(req.RevertAfter); err != nil || d <= 0 {
+					writeLevelResponse(w, http.StatusBadRequest, levelResponse{Error: "invalid revert_after: " + req.RevertAfter})
+					return
+				}
+			}
+			l.SetMinLevelFor(*req.Level, d)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelResponse(w, http.StatusMethodNotAllowed, levelResponse{Error: "method not allowed"})
+			return
+		}
+		writeLevelResponse(w, http.StatusOK, levelResponse{Level: l.Level()})
+	})
+}
+
+func writeLevelResponse(w http.ResponseWriter, code int, res levelResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
+}
+
+// nextLevel returns the registered level just below lv in priority.
+// If lv is the lowest, base is returned so that the levels are cycled.
+func nextLevel(lv, base level.Level) level.Level {
+	next := base
+	for _, l := range level.Levels() {
+		if l.LessThan(lv) {
+			next = l
+		}
+	}
+	return next
+}
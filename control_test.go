@@ -0,0 +1,94 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMinLevelFor(t *testing.T) {
+	assert := assert.New(t)
+
+	logger := New(MinLevel(level.Info))
+
+	t.Run("revert the level after the duration", func(t *testing.T) {
+		logger.SetMinLevelFor(level.Debug, 10*time.Millisecond)
+		logger.SetMinLevelFor(level.Trace, 10*time.Millisecond)
+		assert.Equal(level.Trace, logger.Level())
+		assert.Equal(level.Info, waitForLevel(logger, level.Info))
+	})
+
+	t.Run("SetMinLevel cancels the revert", func(t *testing.T) {
+		logger.SetMinLevelFor(level.Debug, 10*time.Millisecond)
+		logger.SetMinLevel(level.Warn)
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(level.Warn, logger.Level())
+	})
+
+	t.Run("ignore levels that aren't registered", func(t *testing.T) {
+		logger.SetMinLevelFor(level.Level("UNREGISTERED"), time.Millisecond)
+		assert.Equal(level.Warn, logger.Level())
+	})
+}
+
+func TestLevelHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	logger := New(MinLevel(level.Info))
+	h := LevelHandler(logger)
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/level", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("GET responds the current level", func(t *testing.T) {
+		w := serve(http.MethodGet, "")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(`{"level":"INFO"}`, w.Body.String())
+	})
+
+	t.Run("PUT sets the level", func(t *testing.T) {
+		w := serve(http.MethodPut, `{"level":"warning"}`)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.JSONEq(`{"level":"WARN"}`, w.Body.String())
+		assert.Equal(level.Warn, logger.Level())
+	})
+
+	t.Run("PUT with revert_after reverts the level", func(t *testing.T) {
+		w := serve(http.MethodPut, `{"level":"debug","revert_after":"10ms"}`)
+		assert.Equal(http.StatusOK, w.Code)
+		assert.JSONEq(`{"level":"DEBUG"}`, w.Body.String())
+		assert.Equal(level.Warn, waitForLevel(logger, level.Warn))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, body := range []string{`{"level":"extream"}`, `{}`, `{"level":"debug","revert_after":"soon"}`, `not json`} {
+			w := serve(http.MethodPut, body)
+			assert.Equal(http.StatusBadRequest, w.Code, body)
+			assert.Contains(w.Body.String(), `"error"`, body)
+		}
+		assert.Equal(level.Warn, logger.Level())
+
+		w := serve(http.MethodPost, `{"level":"debug"}`)
+		assert.Equal(http.StatusMethodNotAllowed, w.Code)
+		assert.Equal("GET, PUT", w.Header().Get("Allow"))
+	})
+}
+
+// waitForLevel waits for the minimum logging level of l to be want for a second,
+// and returns the last level.
+func waitForLevel(l *Logger, want level.Level) level.Level {
+	deadline := time.Now().Add(time.Second)
+	for l.Level() != want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return l.Level()
+}
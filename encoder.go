@@ -0,0 +1,399 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPooledBufferSize is the max capacity of buffers put back into the pool
+// so that a huge entry doesn't keep its memory.
+const maxPooledBufferSize = 64 << 10
+
+type buffer struct {
+	b []byte
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return &buffer{b: make([]byte, 0, 1024)} },
+}
+
+func getBuffer() *buffer {
+	buf := bufferPool.Get().(*buffer)
+	buf.b = buf.b[:0]
+	return buf
+}
+
+func putBuffer(buf *buffer) {
+	if cap(buf.b) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// jsonItem is a top-level key of an entry encoded by the JSON encoder.
+type jsonItem struct {
+	key  string
+	kind jsonItemKind
+	idx  int // the index of the field
+}
+
+type jsonItemKind uint8
+
+const (
+	itemLevel jsonItemKind = iota
+	itemTime
+	itemMessage
+	itemError
+	itemTrace
+	itemCaller
+	itemField
+	itemMetadata  // a key of the flattened metadata
+	itemContainer // the metadata nested in the container
+)
+
+// appendEntryJSON appends the JSON of the entry with fields and the metadata of c.
+// It is the allocation-free equivalent of formatting with format.JSON,
+// so that the keys are sorted and the last one of the fields with the same key is output.
+// The second returned value is false if the entry can't be encoded,
+// then the entry has to be output through formatter that reports the reason.
+func appendEntryJSON(b []byte, c *config, e *Entry, fields []Field) ([]byte, bool) {
+	var buf [32]jsonItem
+	items, ok := entryJSONItems(buf[:0], c, e, fields)
+	if !ok {
+		return b, false
+	}
+	sortJSONItems(items)
+
+	var err error
+	b = append(b, '{')
+	for i := range items {
+		it := &items[i]
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, it.key)
+		b = append(b, ':')
+		switch it.kind {
+		case itemLevel:
+			b = appendJSONString(b, string(e.Level))
+		case itemTime:
+			b, err = appendJSONTime(b, e.Time)
+		case itemMessage:
+			b = appendJSONString(b, e.Message)
+		case itemError:
+			b, err = appendJSONValue(b, errorValue(e.Error))
+		case itemTrace:
+			b, err = appendJSONValue(b, e.Trace)
+		case itemCaller:
+			b, err = appendJSONValue(b, e.Caller)
+		case itemField:
+			b, err = appendJSONField(b, &fields[it.idx])
+		case itemMetadata:
+			b, err = appendJSONValue(b, c.flatMetadata[it.key])
+		case itemContainer:
+			b, err = appendJSONValue(b, c.metadata)
+		}
+		if err != nil {
+			return b, false
+		}
+	}
+	return append(b, '}'), true
+}
+
+// entryJSONItems appends the top-level keys of the entry in the same way as println.
+// The second returned value is false if the conflict of metadata has to be resolved by merge.
+func entryJSONItems(items []jsonItem, c *config, e *Entry, fields []Field) ([]jsonItem, bool) {
+	n := &c.names
+	items = append(items, jsonItem{key: n.Level, kind: itemLevel}, jsonItem{key: n.Time, kind: itemTime})
+	if e.has(n, n.Message) {
+		items = append(items, jsonItem{key: n.Message, kind: itemMessage})
+	}
+	if e.Error != nil {
+		items = append(items, jsonItem{key: n.Error, kind: itemError})
+	}
+	if e.Trace != nil {
+		items = append(items, jsonItem{key: n.Trace, kind: itemTrace})
+	}
+	if e.Caller != nil {
+		items = append(items, jsonItem{key: n.Caller, kind: itemCaller})
+	}
+
+	nested := !c.flattenMetadata && c.metadata != nil
+	for i := range fields {
+		f := &fields[i]
+		if !outputField(f) || isReservedKey(n, f.Key) || nested && f.Key == n.Metadata || hasField(fields[i+1:], f.Key) {
+			continue
+		}
+		items = append(items, jsonItem{key: f.Key, kind: itemField, idx: i})
+	}
+
+	if nested {
+		return append(items, jsonItem{key: n.Metadata, kind: itemContainer}), true
+	}
+	if !c.flattenMetadata {
+		return items, true
+	}
+	for _, k := range c.metadataKeys {
+		reserved := isReservedKey(n, k)
+		if reserved && e.has(n, k) || !reserved && hasField(fields, k) {
+			// the entry takes precedence by ConflictEntryWins, and the per-entry fields
+			// also by ConflictError. the other conflicts are resolved by merge.
+			if c.metadataConflict == ConflictEntryWins || c.metadataConflict == ConflictError && !reserved {
+				continue
+			}
+			return items, false
+		}
+		items = append(items, jsonItem{key: k, kind: itemMetadata})
+	}
+	return items, true
+}
+
+// sortJSONItems sorts the items by key with the insertion sort,
+// which doesn't allocate and is fast enough for the number of the keys of an entry.
+func sortJSONItems(items []jsonItem) {
+	for i := 1; i < len(items); i++ {
+		for j := i; j > 0 && items[j].key < items[j-1].key; j-- {
+			items[j], items[j-1] = items[j-1], items[j]
+		}
+	}
+}
+
+// outputField reports whether the field is output as a field of the entry.
+// The fields created by Err are output as the error of the entry.
+func outputField(f *Field) bool {
+	return f.typ != skipType && f.typ != errorType
+}
+
+func hasField(fields []Field, k string) bool {
+	for i := range fields {
+		if outputField(&fields[i]) && fields[i].Key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func appendJSONField(b []byte, f *Field) ([]byte, error) {
+	switch f.typ {
+	case stringType:
+		return appendJSONString(b, f.str), nil
+	case int64Type, durationType:
+		return strconv.AppendInt(b, f.integer, 10), nil
+	case uint64Type:
+		return strconv.AppendUint(b, uint64(f.integer), 10), nil
+	case float64Type:
+		return appendJSONFloat(b, math.Float64frombits(uint64(f.integer)), 64)
+	case boolType:
+		return strconv.AppendBool(b, f.integer == 1), nil
+	case timeType:
+		return appendJSONTime(b, time.Unix(0, f.integer).In(f.iface.(*time.Location)))
+	case errorType, namedErrorType:
+		return appendJSONString(b, f.iface.(error).Error()), nil
+	default:
+		return appendJSONValue(b, f.iface)
+	}
+}
+
+// appendJSONValue appends the JSON of v.
+// The basic types are encoded without reflection, and the others are encoded by encoding/json.
+func appendJSONValue(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case string:
+		return appendJSONString(b, v), nil
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	case int:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(b, v, 10), nil
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(b, v, 10), nil
+	case float32:
+		return appendJSONFloat(b, float64(v), 32)
+	case float64:
+		return appendJSONFloat(b, v, 64)
+	case time.Duration:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case time.Time:
+		return appendJSONTime(b, v)
+	case []string:
+		if v == nil {
+			return append(b, "null"...), nil
+		}
+		b = append(b, '[')
+		for i, s := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, s)
+		}
+		return append(b, ']'), nil
+	case []interface{}:
+		if v == nil {
+			return append(b, "null"...), nil
+		}
+		var err error
+		b = append(b, '[')
+		for i, vv := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if b, err = appendJSONValue(b, vv); err != nil {
+				return b, err
+			}
+		}
+		return append(b, ']'), nil
+	case map[string]interface{}:
+		if v == nil {
+			return append(b, "null"...), nil
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var err error
+		b = append(b, '{')
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, k)
+			b = append(b, ':')
+			if b, err = appendJSONValue(b, v[k]); err != nil {
+				return b, err
+			}
+		}
+		return append(b, '}'), nil
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return b, err
+		}
+		return append(b, bs...), nil
+	}
+}
+
+// errJSONTime is returned for the time that encoding/json can't encode in RFC 3339.
+var errJSONTime = errors.New("log: time out of the range of RFC 3339")
+
+// appendJSONTime appends the time in the same way as encoding/json.
+// The year out of [0,9999] and the zone offset of 24 hours or more are unsupported as well as encoding/json.
+func appendJSONTime(b []byte, t time.Time) ([]byte, error) {
+	if y := t.Year(); y < 0 || y > 9999 {
+		return b, errJSONTime
+	}
+	if _, offset := t.Zone(); offset <= -24*60*60 || offset >= 24*60*60 {
+		return b, errJSONTime
+	}
+	b = append(b, '"')
+	b = t.AppendFormat(b, time.RFC3339Nano)
+	return append(b, '"'), nil
+}
+
+// appendJSONFloat appends the float in the same way as encoding/json.
+// NaN and the infinities are unsupported values as well as encoding/json.
+func appendJSONFloat(b []byte, f float64, bits int) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return b, &json.UnsupportedValueError{
+			Value: reflect.ValueOf(f),
+			Str:   strconv.FormatFloat(f, 'g', -1, bits),
+		}
+	}
+
+	fmt := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			fmt = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, fmt, -1, bits)
+	if fmt == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends the quoted string escaped in the same way as encoding/json.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
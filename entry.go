@@ -0,0 +1,180 @@
+package log
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+)
+
+// Entry is a logging entry.
+// An Entry is created for each logging call and passed to Hook before it is formatted.
+type Entry struct {
+	Level   level.Level
+	Time    time.Time
+	Message string
+	Error   error
+	Trace   interface{}
+	Caller  *Frame
+
+	// Fields is the additional fields of the entry that are output next to the fields above.
+	// If a key of Fields is the same as one of the fields above, the field above is output.
+	// If metadata is flattened, Fields take precedence over Metadata.
+	Fields map[string]interface{}
+
+	// Metadata is the metadata of the logger.
+	// Metadata is shared among entries, so it must not be modified.
+	// Use Fields to add fields to the entry instead.
+	Metadata map[string]interface{}
+
+	// stack is the stack trace extracted from the error,
+	// which is rendered into Trace when the entry is output.
+	stack []uintptr
+}
+
+// data returns the map of the entry with the keys named by n that is passed to formatter.
+// The metadata isn't contained.
+func (e *Entry) data(n *format.Schema) map[string]interface{} {
+	data := make(map[string]interface{}, len(e.Fields)+6)
+	for k, v := range e.Fields {
+		if !isReservedKey(n, k) {
+			data[k] = v
+		}
+	}
+
+	data[n.Level] = e.Level
+	data[n.Time] = e.Time
+	if e.Message != "" || e.Error == nil {
+		data[n.Message] = e.Message
+	}
+	if e.Error != nil {
+		data[n.Error] = errorValue(e.Error)
+	}
+	if e.Trace != nil {
+		data[n.Trace] = e.Trace
+	}
+	if e.Caller != nil {
+		data[n.Caller] = e.Caller
+	}
+	return data
+}
+
+// has reports whether the entry outputs the field of the reserved key k named by n.
+func (e *Entry) has(n *format.Schema, k string) bool {
+	switch k {
+	case n.Level, n.Time:
+		return true
+	case n.Message:
+		return e.Message != "" || e.Error == nil
+	case n.Error:
+		return e.Error != nil
+	case n.Trace:
+		return e.Trace != nil
+	case n.Caller:
+		return e.Caller != nil
+	}
+	return false
+}
+
+// maxErrorDepth limits the depth of error chains in case of a cyclic chain.
+const maxErrorDepth = 32
+
+// errorValue returns the value of the error field.
+// The value is the chain of the error from the outermost to the root,
+// and each layer has its type and message.
+// An error that wraps multiple errors like errors.Join has the chains of them in "errors".
+func errorValue(err error) interface{} {
+	return errorChain(err, 0)
+}
+
+func errorChain(err error, depth int) []interface{} {
+	var chain []interface{}
+	for ; err != nil && depth < maxErrorDepth; depth++ {
+		if se, ok := err.(*stackError); ok {
+			err = se.error
+			continue
+		}
+		layer := map[string]interface{}{
+			"type":    reflect.TypeOf(err).String(),
+			"message": err.Error(),
+		}
+		chain = append(chain, layer)
+
+		if errs := unwrapMulti(err); errs != nil {
+			children := make([]interface{}, 0, len(errs))
+			for _, e := range errs {
+				if e != nil {
+					children = append(children, errorChain(e, depth+1))
+				}
+			}
+			layer["errors"] = children
+			break
+		}
+		err = unwrap(err)
+	}
+	return chain
+}
+
+// unwrap returns the error that err wraps by Unwrap or Cause of github.com/pkg/errors.
+func unwrap(err error) error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	case interface{ Cause() error }:
+		return e.Cause()
+	}
+	return nil
+}
+
+func unwrapMulti(err error) []error {
+	if e, ok := err.(interface{ Unwrap() []error }); ok {
+		return e.Unwrap()
+	}
+	return nil
+}
+
+// stackTraceOf returns the program counters of the stack trace of the deepest error
+// that has it in the chain of v.
+// The second returned value is false if v isn't an error or no error in the chain has it.
+func stackTraceOf(v interface{}) ([]uintptr, bool) {
+	err, ok := v.(error)
+	if !ok {
+		return nil, false
+	}
+	st, depth := deepestStackTrace(err, 0)
+	return st, depth >= 0
+}
+
+// deepestStackTrace returns the stack trace of the deepest error in the tree of err
+// and its depth, which is -1 if not found.
+func deepestStackTrace(err error, depth int) ([]uintptr, int) {
+	var (
+		found      []uintptr
+		foundDepth = -1
+	)
+	for ; err != nil && depth < maxErrorDepth; depth++ {
+		if st, ok := stackTraceOfError(err); ok {
+			found, foundDepth = st, depth
+		}
+		if errs := unwrapMulti(err); errs != nil {
+			for _, e := range errs {
+				if st, d := deepestStackTrace(e, depth+1); d > foundDepth {
+					found, foundDepth = st, d
+				}
+			}
+			break
+		}
+		err = unwrap(err)
+	}
+	return found, foundDepth
+}
+
+// isReservedKey reports whether k is one of the keys of the fields that Entry has named by n.
+func isReservedKey(n *format.Schema, k string) bool {
+	switch k {
+	case n.Level, n.Time, n.Message, n.Error, n.Trace, n.Caller:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorValue(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("the chain of Unwrap is rendered", func(t *testing.T) {
+		root := stderrors.New("root")
+		err := fmt.Errorf("outer: %w", root)
+		assert.Equal([]interface{}{
+			map[string]interface{}{"type": "*fmt.wrapError", "message": "outer: root"},
+			map[string]interface{}{"type": "*errors.errorString", "message": "root"},
+		}, errorValue(err))
+	})
+
+	t.Run("the chain of Cause is rendered", func(t *testing.T) {
+		err := errors.WithMessage(stderrors.New("root"), "outer")
+		assert.Equal([]interface{}{
+			map[string]interface{}{"type": "*errors.withMessage", "message": "outer: root"},
+			map[string]interface{}{"type": "*errors.errorString", "message": "root"},
+		}, errorValue(err))
+	})
+
+	t.Run("multiple errors are rendered as a tree", func(t *testing.T) {
+		err := fmt.Errorf("outer: %w", stderrors.Join(
+			stderrors.New("first"),
+			fmt.Errorf("second: %w", stderrors.New("root")),
+		))
+		assert.Equal([]interface{}{
+			map[string]interface{}{"type": "*fmt.wrapError", "message": "outer: first\nsecond: root"},
+			map[string]interface{}{
+				"type":    "*errors.joinError",
+				"message": "first\nsecond: root",
+				"errors": []interface{}{
+					[]interface{}{
+						map[string]interface{}{"type": "*errors.errorString", "message": "first"},
+					},
+					[]interface{}{
+						map[string]interface{}{"type": "*fmt.wrapError", "message": "second: root"},
+						map[string]interface{}{"type": "*errors.errorString", "message": "root"},
+					},
+				},
+			},
+		}, errorValue(err))
+	})
+}
+
+func TestStackTraceOf(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := stackTraceOf("not error")
+	assert.False(ok)
+
+	_, ok = stackTraceOf(stderrors.New("no stack"))
+	assert.False(ok)
+
+	root := errors.New("root")
+	wrapped := errors.Wrap(fmt.Errorf("middle: %w", root), "outer")
+
+	want := root.(interface{ StackTrace() errors.StackTrace }).StackTrace()
+	st, ok := stackTraceOf(wrapped)
+	assert.True(ok)
+	assert.Len(st, len(want))
+	assert.Equal(uintptr(want[0]), st[0])
+
+	joined := stderrors.Join(stderrors.New("no stack"), fmt.Errorf("middle: %w", root))
+	st, ok = stackTraceOf(joined)
+	assert.True(ok)
+	assert.Equal(uintptr(want[0]), st[0])
+}
@@ -0,0 +1,161 @@
+package log
+
+import (
+	"math"
+	"time"
+)
+
+type fieldType uint8
+
+const (
+	skipType fieldType = iota
+	stringType
+	int64Type
+	uint64Type
+	float64Type
+	boolType
+	durationType
+	timeType
+	errorType
+	namedErrorType
+	anyType
+)
+
+// Field is a typed field of an entry.
+// Field is created by the constructors like String and Int,
+// which don't allocate for the values of basic types.
+type Field struct {
+	Key     string
+	typ     fieldType
+	integer int64
+	str     string
+	iface   interface{}
+}
+
+// String returns Field of a string value.
+func String(key string, v string) Field {
+	return Field{Key: key, typ: stringType, str: v}
+}
+
+// Int returns Field of an int value.
+func Int(key string, v int) Field {
+	return Int64(key, int64(v))
+}
+
+// Int64 returns Field of an int64 value.
+func Int64(key string, v int64) Field {
+	return Field{Key: key, typ: int64Type, integer: v}
+}
+
+// Uint returns Field of an uint value.
+func Uint(key string, v uint) Field {
+	return Uint64(key, uint64(v))
+}
+
+// Uint64 returns Field of an uint64 value.
+func Uint64(key string, v uint64) Field {
+	return Field{Key: key, typ: uint64Type, integer: int64(v)}
+}
+
+// Float64 returns Field of a float64 value.
+func Float64(key string, v float64) Field {
+	return Field{Key: key, typ: float64Type, integer: int64(math.Float64bits(v))}
+}
+
+// Bool returns Field of a bool value.
+func Bool(key string, v bool) Field {
+	var i int64
+	if v {
+		i = 1
+	}
+	return Field{Key: key, typ: boolType, integer: i}
+}
+
+// Duration returns Field of a time.Duration value.
+// The value is output as nanoseconds like encoding/json does.
+func Duration(key string, v time.Duration) Field {
+	return Field{Key: key, typ: durationType, integer: int64(v)}
+}
+
+// minUnixNano and maxUnixNano are the range of time that UnixNano can represent.
+var (
+	minUnixNano = time.Unix(0, math.MinInt64)
+	maxUnixNano = time.Unix(0, math.MaxInt64)
+)
+
+// Time returns Field of a time.Time value.
+func Time(key string, v time.Time) Field {
+	if v.Before(minUnixNano) || v.After(maxUnixNano) {
+		return Any(key, v)
+	}
+	return Field{Key: key, typ: timeType, integer: v.UnixNano(), iface: v.Location()}
+}
+
+// Err returns Field of an error that is output as the error of the entry.
+// If err is nil, the field is ignored.
+func Err(err error) Field {
+	if err == nil {
+		return Field{typ: skipType}
+	}
+	return Field{Key: "error", typ: errorType, iface: err}
+}
+
+// NamedErr returns Field of an error whose message is output as the value of key.
+// If err is nil, the field is ignored.
+func NamedErr(key string, err error) Field {
+	if err == nil {
+		return Field{typ: skipType}
+	}
+	return Field{Key: key, typ: namedErrorType, iface: err}
+}
+
+// Any returns Field of an arbitrary value.
+// The value is encoded with reflection if it isn't a basic type.
+func Any(key string, v interface{}) Field {
+	return Field{Key: key, typ: anyType, iface: v}
+}
+
+// value returns the value of the field that is passed to formatter.
+func (f Field) value() interface{} {
+	switch f.typ {
+	case stringType:
+		return f.str
+	case int64Type:
+		return f.integer
+	case uint64Type:
+		return uint64(f.integer)
+	case float64Type:
+		return math.Float64frombits(uint64(f.integer))
+	case boolType:
+		return f.integer == 1
+	case durationType:
+		return time.Duration(f.integer)
+	case timeType:
+		return time.Unix(0, f.integer).In(f.iface.(*time.Location))
+	case errorType, namedErrorType:
+		return f.iface.(error).Error()
+	default:
+		return f.iface
+	}
+}
+
+// applyFields sets fields to the entry.
+// The first field created by Err is set as the error of the entry if the entry has no error,
+// and the others are ignored.
+func applyFields(e *Entry, fields []Field) {
+	for _, f := range fields {
+		switch f.typ {
+		case skipType:
+			continue
+		case errorType:
+			if e.Error == nil {
+				e.Error = f.iface.(error)
+			}
+			continue
+		}
+		if e.Fields == nil {
+			e.Fields = make(map[string]interface{}, len(fields))
+		}
+		e.Fields[f.Key] = f.value()
+	}
+}
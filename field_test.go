@@ -0,0 +1,176 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFields() []Field {
+	return []Field{
+		String("string", "a<b>&\"c\"\n "),
+		Int("int", -1),
+		Int64("int64", math.MaxInt64),
+		Uint("uint", 1),
+		Uint64("uint64", math.MaxUint64),
+		Float64("float64", 1.5e-7),
+		Bool("bool", true),
+		Duration("duration", time.Second),
+		Time("time_field", time.Date(2019, 10, 22, 16, 50, 17, 1, time.FixedZone("JST", 9*60*60))),
+		NamedErr("cause", errors.New("cause")),
+		Any("any", map[string]interface{}{"nested": []interface{}{1, "two", nil}}),
+		Any("struct", struct{ A int }{A: 1}),
+		Err(nil),
+	}
+}
+
+func TestFields(t *testing.T) {
+	assert := assert.New(t)
+
+	// encode outputs the entry both by the JSON encoder of JSONLayout and through format.JSON,
+	// asserts that the outputs are the same, and decodes the first entry of them.
+	encode := func(log func(*Logger), ops ...Option) map[string]interface{} {
+		ops = append(ops, nowFunc(func() time.Time { return time.Time{} }), withoutTrace(true))
+
+		fastBuf := bytes.NewBuffer(nil)
+		fast := New(append(ops, Output(fastBuf), FormatLayout(JSONLayout))...)
+		assert.True(fast.load().fastJSON)
+		log(fast)
+
+		slowBuf := bytes.NewBuffer(nil)
+		log(New(append(ops, Output(slowBuf), Format(format.JSON))...))
+		assert.Equal(slowBuf.String(), fastBuf.String())
+
+		var mp map[string]interface{}
+		assert.NoError(json.NewDecoder(fastBuf).Decode(&mp))
+		return mp
+	}
+
+	t.Run("the JSON encoder outputs the same as format.JSON", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.InfoFields("info", testFields()...)
+		}, Metadata(map[string]interface{}{"service": "book", "count": 1.5}))
+		assert.Equal("a<b>&\"c\"\n ", fast["string"])
+		assert.Equal(float64(1e9), fast["duration"])
+		assert.Equal("2019-10-22T16:50:17.000000001+09:00", fast["time_field"])
+		assert.Equal("cause", fast["cause"])
+		assert.Equal(map[string]interface{}{"service": "book", "count": 1.5}, fast["meta"])
+	})
+
+	t.Run("flattened metadata is overridden by fields", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.InfoFields("info", String("service", "shelf"))
+		}, Metadata(map[string]interface{}{"service": "book", "request_id": "req1"}), FlattenMetadata(true))
+		assert.Equal("shelf", fast["service"])
+		assert.Equal("req1", fast["request_id"])
+	})
+
+	t.Run("the last one of the fields with the same key is output", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.InfoFields("info", String("a", "1"), Int("b", 1), String("a", "2"), Err(nil))
+		})
+		assert.Equal("2", fast["a"])
+	})
+
+	t.Run("Err sets the error of the entry", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.WarnFields("warn", Err(errors.New("error")), String("level", "ignored"), Err(errors.New("ignored")))
+		})
+		assert.Equal("WARN", fast["level"])
+		assert.Equal("warn", fast["message"])
+		assert.Equal(errorJSON("*errors.errorString", "error"), fast["error"])
+	})
+
+	t.Run("ErrorFields outputs the error", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.ErrorFields(errors.New("error"), String("request_id", "req1"))
+		})
+		assert.Nil(fast["message"])
+		assert.Equal(errorJSON("*errors.errorString", "error"), fast["error"])
+	})
+
+	t.Run("metadata conflicted is reported", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.DebugFields("debug")
+		}, Metadata(map[string]interface{}{"message": "meta"}), FlattenMetadata(true))
+		assert.Equal(errorJSON("*errors.fundamental", "the key of metadata conflicted: key=message"), fast["error"])
+	})
+
+	t.Run("unsupported values are reported", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.InfoFields("info", Float64("nan", math.NaN()))
+		})
+		assert.Equal(errorJSON("*json.UnsupportedValueError", "json: unsupported value: NaN"), fast["error"])
+	})
+
+	t.Run("nil slices and maps are null", func(t *testing.T) {
+		fast := encode(func(l *Logger) {
+			l.InfoFields("info",
+				Any("strings", []string(nil)),
+				Any("values", []interface{}(nil)),
+				Any("map", map[string]interface{}(nil)),
+			)
+		})
+		for _, k := range []string{"strings", "values", "map"} {
+			v, ok := fast[k]
+			assert.True(ok, k)
+			assert.Nil(v, k)
+		}
+	})
+
+	t.Run("times out of the range of RFC 3339 are reported", func(t *testing.T) {
+		for _, tm := range []time.Time{
+			time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(-1, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, 1, 1, 0, 0, 0, 0, time.FixedZone("", 24*60*60)),
+		} {
+			fast := encode(func(l *Logger) {
+				l.InfoFields("info", Time("time_field", tm), Any("any_time", tm))
+			})
+			assert.NotNil(fast["error"], tm.String())
+		}
+	})
+}
+
+func TestFieldsAllocs(t *testing.T) {
+	logger := New(Output(ioutil.Discard), FormatLayout(JSONLayout), MinLevel(level.Warn))
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.InfoFields("info", String("request_id", "req1"), Int("count", 1), Duration("elapsed", time.Second))
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", "<&>", "\"\\/", "\x00\x1f\b\f\n\r\t", "  ", "日本語", "\xff"} {
+		want, _ := json.Marshal(s)
+		assert.Equal(t, string(want), string(appendJSONString(nil, s)))
+	}
+}
+
+func TestAppendJSONFloat(t *testing.T) {
+	for _, f := range []float64{0, 1, -1.5, 1e-7, 1e20, 1e21, 123456789.123, math.SmallestNonzeroFloat64} {
+		want, _ := json.Marshal(f)
+		got, err := appendJSONFloat(nil, f, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	}
+	for _, f := range []float32{0, 1.1, 1e-7, 1e21} {
+		want, _ := json.Marshal(f)
+		got, err := appendJSONFloat(nil, float64(f), 32)
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, want := json.Marshal(f)
+		_, err := appendJSONFloat(nil, f, 64)
+		assert.EqualError(t, err, want.Error())
+	}
+}
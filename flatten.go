@@ -0,0 +1,78 @@
+package log
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// defaultFlattenSeparator is the separator of the keys renamed by ConflictRename
+// if the separator isn't set by FlattenNested.
+const defaultFlattenSeparator = "."
+
+// flattenNested returns a new map that has the values nested in md with the keys joined with sep.
+func flattenNested(md map[string]interface{}, sep string, maxDepth int) map[string]interface{} {
+	m := make(map[string]interface{}, len(md))
+	for k, v := range md {
+		flattenValue(m, k, v, sep, 1, maxDepth)
+	}
+	return m
+}
+
+func flattenValue(m map[string]interface{}, key string, v interface{}, sep string, depth, maxDepth int) {
+	if maxDepth <= 0 || depth < maxDepth {
+		if nested, ok := nestedMap(v); ok && len(nested) > 0 {
+			for k, nv := range nested {
+				flattenValue(m, key+sep+k, nv, sep, depth+1, maxDepth)
+			}
+			return
+		}
+	}
+	m[key] = v
+}
+
+// nestedMap returns v as a map if it is a map with string keys or a struct.
+func nestedMap(v interface{}) (map[string]interface{}, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, true
+	}
+	switch v.(type) {
+	case nil, json.Marshaler, encoding.TextMarshaler, error, fmt.Stringer:
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m, true
+	case reflect.Ptr:
+		if rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+			return nil, false
+		}
+	case reflect.Struct:
+	default:
+		return nil, false
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenNested(t *testing.T) {
+	assert := assert.New(t)
+
+	type request struct {
+		Method string `json:"method"`
+		Path   string `json:"path,omitempty"`
+		Secret string `json:"-"`
+	}
+	md := map[string]interface{}{
+		"service": "book",
+		"http": map[string]interface{}{
+			"request": &request{Method: "GET", Secret: "s"},
+			"status":  200,
+		},
+		"labels": map[string]string{"env": "prod"},
+		"tags":   []string{"a", "b"},
+		"empty":  map[string]interface{}{},
+		"time":   time.Time{},
+	}
+
+	t.Run("nested maps and structs are flattened", func(t *testing.T) {
+		assert.Equal(map[string]interface{}{
+			"service":             "book",
+			"http.request.method": "GET",
+			"http.status":         200,
+			"labels.env":          "prod",
+			"tags":                []string{"a", "b"},
+			"empty":               map[string]interface{}{},
+			"time":                time.Time{},
+		}, flattenNested(md, ".", 0))
+	})
+
+	t.Run("values deeper than max depth are left nested", func(t *testing.T) {
+		m := flattenNested(md, "_", 2)
+		assert.Equal(&request{Method: "GET", Secret: "s"}, m["http_request"])
+		assert.Equal(200, m["http_status"])
+	})
+
+	t.Run("logger outputs flattened nested metadata", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		lg := New(
+			Format(format.JSON),
+			Output(buf),
+			Metadata(map[string]interface{}{
+				"http":  map[string]interface{}{"method": "GET"},
+				"level": map[string]interface{}{"name": "meta"},
+			}),
+			FlattenMetadata(true),
+			FlattenNested(".", 0),
+		)
+		lg.update(nowFunc(func() time.Time { return time.Time{} }))
+		lg.update(withoutTrace(true))
+
+		lg.Info("info")
+		assert.Equal(`{"http.method":"GET","level":"INFO","level.name":"meta","message":"info","time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal(map[string]interface{}{"method": "GET"}, format.Unflatten(mp, ".")["http"])
+
+		buf.Reset()
+		lg.InfoFields("info", String("http.method", "POST"))
+		assert.Equal(`{"http.method":"POST","level":"INFO","level.name":"meta","message":"info","time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("renamed keys are prefixed with the separator", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		lg := New(
+			Format(format.JSON),
+			Output(buf),
+			Metadata(map[string]interface{}{"message": "meta"}),
+			FlattenMetadata(true),
+			FlattenNested("_", 0),
+			MetadataConflict(ConflictRename),
+		)
+		lg.update(nowFunc(func() time.Time { return time.Time{} }))
+		lg.update(withoutTrace(true))
+
+		lg.Info("info")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("meta", mp["meta_message"])
+		assert.Equal("info", mp["message"])
+	})
+}
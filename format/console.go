@@ -0,0 +1,243 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorGray   = "\x1b[90m"
+)
+
+var levelColors = map[string]string{
+	"TRACE": colorGray,
+	"DEBUG": colorGray,
+	"INFO":  colorBlue,
+	"WARN":  colorYellow,
+	"ERROR": colorRed,
+	"PANIC": colorRed,
+	"FATAL": colorRed,
+}
+
+// consoleTimeFormat is the short time format of Console.
+const consoleTimeFormat = "15:04:05.000"
+
+var (
+	stdoutColorOnce sync.Once
+	stdoutColor     bool
+)
+
+// Console is format of message output for local development.
+// It outputs one line per entry that has a level badge, a short timestamp, a message
+// and the other fields as key=value, followed by the stack trace indented on the following lines.
+// The error chain output by Logger is the outermost message, which is the message of the entry without one,
+// followed by its causes as "caused by" lines.
+// The output is colored if stdout is a terminal and the NO_COLOR environment variable isn't set.
+// ConsoleFor colors it along another output.
+func Console(v map[string]interface{}) (string, error) {
+	stdoutColorOnce.Do(func() { stdoutColor = ColorEnabled(os.Stdout) })
+	return console(v, DefaultSchema, stdoutColor)
+}
+
+// ConsoleFor returns Console format that is colored
+// if w is a terminal and the NO_COLOR environment variable isn't set.
+func ConsoleFor(w io.Writer) func(map[string]interface{}) (string, error) {
+	return ConsoleColor(ColorEnabled(w))
+}
+
+// ConsoleColor returns Console format that is colored if color is true.
+func ConsoleColor(color bool) func(map[string]interface{}) (string, error) {
+	if color {
+		return consoleColored
+	}
+	return consolePlain
+}
+
+func consoleColored(v map[string]interface{}) (string, error) {
+	return console(v, DefaultSchema, true)
+}
+
+func consolePlain(v map[string]interface{}) (string, error) {
+	return console(v, DefaultSchema, false)
+}
+
+// ConsoleSchema returns Console format that lays out the fields of the names of s
+// and is colored if color is true.
+func ConsoleSchema(s Schema, color bool) func(map[string]interface{}) (string, error) {
+	s = s.WithDefaults()
+	return func(v map[string]interface{}) (string, error) {
+		return console(v, s, color)
+	}
+}
+
+// ColorEnabled reports whether the output to w is colored,
+// which is true if w is a terminal and the NO_COLOR environment variable isn't set.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func console(v map[string]interface{}, s Schema, color bool) (string, error) {
+	var b strings.Builder
+
+	lv := fmt.Sprint(v[s.Level])
+	if c, ok := levelColors[lv]; ok && color {
+		fmt.Fprintf(&b, "%s%-5s%s", c, lv, colorReset)
+	} else {
+		fmt.Fprintf(&b, "%-5s", lv)
+	}
+
+	if t, ok := v[s.Time].(time.Time); ok {
+		b.WriteByte(' ')
+		writeColored(&b, t.Format(consoleTimeFormat), colorDim, color)
+	}
+
+	// the error chain output by Logger is rendered as the outermost message
+	// followed by the causes on the following lines, instead of the pairs of the layers.
+	chain, isChain := errorChain(v[s.Error])
+	if msg, ok := v[s.Message]; ok {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(msg))
+	} else if isChain {
+		b.WriteByte(' ')
+		writeColored(&b, layerMessage(chain[0]), colorRed, color)
+	}
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		switch k {
+		case s.Level, s.Time, s.Message, s.Trace:
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var kv strings.Builder
+		if k == s.Error && isChain {
+			if _, ok := v[s.Message]; !ok {
+				continue // the message is already output
+			}
+			writeLogfmt(&kv, k, layerMessage(chain[0]))
+		} else {
+			writeLogfmt(&kv, k, v[k])
+		}
+		if kv.Len() == 0 {
+			continue // an empty map has no pairs
+		}
+		b.WriteByte(' ')
+		if k == s.Error {
+			writeColored(&b, kv.String(), colorRed, color)
+			continue
+		}
+		writeColored(&b, kv.String(), colorDim, color)
+	}
+
+	if isChain {
+		writeConsoleCauses(&b, chain, "", color)
+	}
+	if trace, ok := v[s.Trace]; ok {
+		writeConsoleTrace(&b, trace)
+	}
+	return b.String(), nil
+}
+
+// errorChain returns the layers of the error chain output by Logger,
+// which have the message and the type of each error and the chains of the joined errors.
+func errorChain(v interface{}) ([]map[string]interface{}, bool) {
+	vs, ok := v.([]interface{})
+	if !ok || len(vs) == 0 {
+		return nil, false
+	}
+	chain := make([]map[string]interface{}, len(vs))
+	for i, l := range vs {
+		layer, ok := l.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if _, ok := layer["message"]; !ok {
+			return nil, false
+		}
+		chain[i] = layer
+	}
+	return chain, true
+}
+
+// layerMessage returns the message of the layer in a line.
+func layerMessage(layer map[string]interface{}) string {
+	return strings.Replace(fmt.Sprint(layer["message"]), "\n", "; ", -1)
+}
+
+// writeConsoleCauses writes the causes of the first layer of the chain as "caused by" lines
+// indented under it. The causes are the layers after the first one and the chains of the joined errors,
+// whose causes are indented further.
+func writeConsoleCauses(b *strings.Builder, chain []map[string]interface{}, indent string, color bool) {
+	indent += "    "
+	for i, layer := range chain {
+		childIndent := indent
+		if i > 0 {
+			writeConsoleCause(b, layer, indent, color)
+			childIndent += "    "
+		}
+		children, _ := layer["errors"].([]interface{})
+		for _, c := range children {
+			child, ok := errorChain(c)
+			if !ok {
+				continue
+			}
+			writeConsoleCause(b, child[0], childIndent, color)
+			writeConsoleCauses(b, child, childIndent, color)
+		}
+	}
+}
+
+func writeConsoleCause(b *strings.Builder, layer map[string]interface{}, indent string, color bool) {
+	b.WriteString("\n")
+	b.WriteString(indent)
+	writeColored(b, "caused by: "+layerMessage(layer), colorRed, color)
+}
+
+func writeColored(b *strings.Builder, s, c string, color bool) {
+	if !color {
+		b.WriteString(s)
+		return
+	}
+	b.WriteString(c)
+	b.WriteString(s)
+	b.WriteString(colorReset)
+}
+
+// writeConsoleTrace writes each frame of the trace indented on a new line.
+func writeConsoleTrace(b *strings.Builder, trace interface{}) {
+	rv := reflect.ValueOf(trace)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		b.WriteString("\n    ")
+		b.WriteString(fmt.Sprint(trace))
+		return
+	}
+	for i := 0; i < rv.Len(); i++ {
+		frame := fmt.Sprintf("%+v", rv.Index(i).Interface())
+		for _, line := range strings.Split(frame, "\n") {
+			b.WriteString("\n    ")
+			b.WriteString(strings.TrimLeft(line, "\t"))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package format
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsole(t *testing.T) {
+	assert := assert.New(t)
+
+	v := map[string]interface{}{
+		"level":   "WARN",
+		"time":    time.Date(2019, 10, 22, 16, 50, 17, 123000000, time.UTC),
+		"message": "warn",
+		"meta":    map[string]interface{}{"request_id": "req1"},
+		"trace":   []string{"main.main main.go:26", "runtime.main proc.go:203"},
+	}
+
+	t.Run("without color", func(t *testing.T) {
+		s, err := ConsoleColor(false)(v)
+		assert.NoError(err)
+		assert.Equal(`WARN  16:50:17.123 warn meta.request_id=req1
+    main.main main.go:26
+    runtime.main proc.go:203`, s)
+	})
+
+	t.Run("with color", func(t *testing.T) {
+		s, err := ConsoleColor(true)(v)
+		assert.NoError(err)
+		assert.Equal("\x1b[33mWARN \x1b[0m \x1b[2m16:50:17.123\x1b[0m warn \x1b[2mmeta.request_id=req1\x1b[0m\n"+
+			"    main.main main.go:26\n"+
+			"    runtime.main proc.go:203", s)
+	})
+
+	t.Run("error is output as a field", func(t *testing.T) {
+		s, err := ConsoleColor(false)(map[string]interface{}{
+			"level": "ERROR",
+			"error": "*errors.errorString: error",
+		})
+		assert.NoError(err)
+		assert.Equal(`ERROR error="*errors.errorString: error"`, s)
+	})
+	t.Run("the error chain is output as the message and the causes", func(t *testing.T) {
+		chain := []interface{}{
+			map[string]interface{}{"type": "*fmt.wrapError", "message": "wrap: root"},
+			map[string]interface{}{"type": "*errors.errorString", "message": "root"},
+		}
+		s, err := ConsoleColor(false)(map[string]interface{}{"level": "ERROR", "error": chain})
+		assert.NoError(err)
+		assert.Equal("ERROR wrap: root\n"+
+			"    caused by: root", s)
+
+		s, err = ConsoleColor(false)(map[string]interface{}{"level": "ERROR", "message": "failed", "error": chain})
+		assert.NoError(err)
+		assert.Equal("ERROR failed error=\"wrap: root\"\n"+
+			"    caused by: root", s)
+	})
+
+	t.Run("the joined errors are indented under their layer", func(t *testing.T) {
+		s, err := ConsoleColor(false)(map[string]interface{}{
+			"level": "ERROR",
+			"error": []interface{}{
+				map[string]interface{}{"type": "*fmt.wrapError", "message": "outer: first\nsecond: root"},
+				map[string]interface{}{
+					"type":    "*errors.joinError",
+					"message": "first\nsecond: root",
+					"errors": []interface{}{
+						[]interface{}{map[string]interface{}{"type": "*errors.errorString", "message": "first"}},
+						[]interface{}{
+							map[string]interface{}{"type": "*fmt.wrapError", "message": "second: root"},
+							map[string]interface{}{"type": "*errors.errorString", "message": "root"},
+						},
+					},
+				},
+			},
+		})
+		assert.NoError(err)
+		assert.Equal("ERROR outer: first; second: root\n"+
+			"    caused by: first; second: root\n"+
+			"        caused by: first\n"+
+			"        caused by: second: root\n"+
+			"            caused by: root", s)
+	})
+}
+
+func TestConsoleFor(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := ConsoleFor(bytes.NewBuffer(nil))(map[string]interface{}{"level": "INFO", "message": "info"})
+	assert.NoError(err)
+	assert.Equal("INFO  info", s)
+
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	assert.False(ColorEnabled(os.Stdout))
+}
@@ -0,0 +1,151 @@
+package format
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Logfmt is format of message output.
+// It outputs key=value pairs separated by spaces.
+// level, time and message are output first, then the other keys are output in sorted order.
+// Nested maps and slices are flattened into dotted keys like meta.request_id and trace.0.
+func Logfmt(v map[string]interface{}) (string, error) {
+	return logfmt(v, DefaultSchema)
+}
+
+// LogfmtSchema returns Logfmt format that outputs the level, the time and the message
+// of the names of s first.
+func LogfmtSchema(s Schema) func(map[string]interface{}) (string, error) {
+	s = s.WithDefaults()
+	return func(v map[string]interface{}) (string, error) {
+		return logfmt(v, s)
+	}
+}
+
+func logfmt(v map[string]interface{}, s Schema) (string, error) {
+	var b strings.Builder
+	prior := [...]string{s.Level, s.Time, s.Message}
+	for _, k := range prior {
+		if vv, ok := v[k]; ok {
+			writeLogfmt(&b, k, vv)
+		}
+	}
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		if k != s.Level && k != s.Time && k != s.Message {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeLogfmt(&b, k, v[k])
+	}
+	return b.String(), nil
+}
+
+func writeLogfmt(b *strings.Builder, key string, v interface{}) {
+	switch vv := v.(type) {
+	case nil:
+		writeLogfmtPair(b, key, "null")
+		return
+	case string:
+		writeLogfmtPair(b, key, quoteLogfmt(vv))
+		return
+	case time.Time:
+		writeLogfmtPair(b, key, vv.Format(time.RFC3339Nano))
+		return
+	case error:
+		writeLogfmtPair(b, key, quoteLogfmt(vv.Error()))
+		return
+	case fmt.Stringer:
+		writeLogfmtPair(b, key, quoteLogfmt(vv.String()))
+		return
+	case encoding.TextMarshaler:
+		t, err := vv.MarshalText()
+		if err != nil {
+			writeLogfmtPair(b, key, quoteLogfmt(err.Error()))
+			return
+		}
+		writeLogfmtPair(b, key, quoteLogfmt(string(t)))
+		return
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeLogfmt(b, key+"."+k, rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())).Interface())
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		for i := 0; i < rv.Len(); i++ {
+			writeLogfmt(b, key+"."+strconv.Itoa(i), rv.Index(i).Interface())
+		}
+		return
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			writeLogfmtPair(b, key, "null")
+			return
+		}
+		writeLogfmt(b, key, rv.Elem().Interface())
+		return
+	case reflect.String:
+		writeLogfmtPair(b, key, quoteLogfmt(rv.String()))
+		return
+	}
+	writeLogfmtPair(b, key, quoteLogfmt(fmt.Sprint(v)))
+}
+
+func writeLogfmtPair(b *strings.Builder, key, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(sanitizeLogfmtKey(key))
+	b.WriteByte('=')
+	b.WriteString(value)
+}
+
+// sanitizeLogfmtKey replaces the characters that can't be used in a key with underscores.
+func sanitizeLogfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// quoteLogfmt quotes s if it is empty or has characters that need to be quoted.
+func quoteLogfmt(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
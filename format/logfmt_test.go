@@ -0,0 +1,59 @@
+package format
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogfmt(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("level, time and message come first and others are sorted", func(t *testing.T) {
+		s, err := Logfmt(map[string]interface{}{
+			"user_id":    "user1",
+			"message":    "info",
+			"request_id": "req1",
+			"level":      "INFO",
+			"time":       time.Date(2019, 10, 22, 16, 50, 17, 0, time.UTC),
+			"count":      3,
+			"ok":         true,
+		})
+		assert.NoError(err)
+		assert.Equal("level=INFO time=2019-10-22T16:50:17Z message=info count=3 ok=true request_id=req1 user_id=user1", s)
+	})
+
+	t.Run("values are quoted and escaped", func(t *testing.T) {
+		s, err := Logfmt(map[string]interface{}{
+			"message": `say "hello" world`,
+			"empty":   "",
+			"eq":      "a=b",
+			"newline": "a\nb",
+			"nil":     nil,
+			"error":   errors.New("not found"),
+		})
+		assert.NoError(err)
+		assert.Equal(`message="say \"hello\" world" empty="" eq="a=b" error="not found" newline="a\nb" nil=null`, s)
+	})
+
+	t.Run("nested metadata and slices are flattened", func(t *testing.T) {
+		s, err := Logfmt(map[string]interface{}{
+			"level": "WARN",
+			"meta": map[string]interface{}{
+				"request_id": "req1",
+				"http":       map[string]interface{}{"method": "GET"},
+			},
+			"trace": []string{"main.main main.go:26", "runtime.main proc.go:203"},
+		})
+		assert.NoError(err)
+		assert.Equal(`level=WARN meta.http.method=GET meta.request_id=req1 trace.0="main.main main.go:26" trace.1="runtime.main proc.go:203"`, s)
+	})
+
+	t.Run("invalid characters in keys are replaced", func(t *testing.T) {
+		s, err := Logfmt(map[string]interface{}{"a key=": 1})
+		assert.NoError(err)
+		assert.Equal("a_key_=1", s)
+	})
+}
@@ -0,0 +1,83 @@
+package format
+
+import "fmt"
+
+// Schema is the names of the keys of the fields that the logger outputs for an entry.
+// The empty names are the ones of DefaultSchema.
+type Schema struct {
+	Level    string
+	Time     string
+	Message  string
+	Error    string
+	Trace    string
+	Caller   string
+	Metadata string // the key of the metadata nested if it isn't flattened
+}
+
+// DefaultSchema is the schema that the logger uses by default.
+var DefaultSchema = Schema{
+	Level:    "level",
+	Time:     "time",
+	Message:  "message",
+	Error:    "error",
+	Trace:    "trace",
+	Caller:   "caller",
+	Metadata: "meta",
+}
+
+// LogstashSchema is the schema with the names of the JSON output by logstash-logback-encoder,
+// like "@timestamp" and "stack_trace".
+var LogstashSchema = Schema{
+	Level:    "level",
+	Time:     "@timestamp",
+	Message:  "message",
+	Error:    "error",
+	Trace:    "stack_trace",
+	Caller:   "caller",
+	Metadata: "meta",
+}
+
+// GCPSchema is the schema with the names of the special fields of the structured logging
+// of Google Cloud Logging, like "severity".
+// Only the names are changed, so the levels like WARN, which aren't the severities
+// of Cloud Logging, are recognized as DEFAULT.
+var GCPSchema = Schema{
+	Level:    "severity",
+	Time:     "time",
+	Message:  "message",
+	Error:    "error",
+	Trace:    "stack_trace",
+	Caller:   "caller",
+	Metadata: "meta",
+}
+
+// WithDefaults returns the schema whose empty names are replaced with the ones of DefaultSchema.
+func (s Schema) WithDefaults() Schema {
+	fill := func(name *string, def string) {
+		if *name == "" {
+			*name = def
+		}
+	}
+	fill(&s.Level, DefaultSchema.Level)
+	fill(&s.Time, DefaultSchema.Time)
+	fill(&s.Message, DefaultSchema.Message)
+	fill(&s.Error, DefaultSchema.Error)
+	fill(&s.Trace, DefaultSchema.Trace)
+	fill(&s.Caller, DefaultSchema.Caller)
+	fill(&s.Metadata, DefaultSchema.Metadata)
+	return s
+}
+
+// Validate returns an error if two fields of the schema have the same name.
+func (s Schema) Validate() error {
+	s = s.WithDefaults()
+	names := []string{s.Level, s.Time, s.Message, s.Error, s.Trace, s.Caller, s.Metadata}
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if a == b {
+				return fmt.Errorf("format: duplicate field name %q in schema", a)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package format
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("empty names are the default ones", func(t *testing.T) {
+		s := Schema{Level: "severity"}.WithDefaults()
+		assert.Equal("severity", s.Level)
+		assert.Equal(DefaultSchema.Message, s.Message)
+		assert.Equal(DefaultSchema.Metadata, s.Metadata)
+	})
+
+	t.Run("duplicate names are invalid", func(t *testing.T) {
+		assert.NoError(DefaultSchema.Validate())
+		assert.NoError(LogstashSchema.Validate())
+		assert.NoError(GCPSchema.Validate())
+		assert.EqualError(Schema{Message: "msg", Error: "msg"}.Validate(), `format: duplicate field name "msg" in schema`)
+		assert.Error(Schema{Caller: "level"}.Validate())
+	})
+
+	v := map[string]interface{}{
+		"severity":    "WARN",
+		"@timestamp":  time.Date(2019, 10, 22, 16, 50, 17, 123000000, time.UTC),
+		"msg":         "warn",
+		"err":         "failed",
+		"stack_trace": []string{"main.main main.go:26"},
+	}
+	s := Schema{Level: "severity", Time: "@timestamp", Message: "msg", Error: "err", Trace: "stack_trace"}
+
+	t.Run("logfmt", func(t *testing.T) {
+		out, err := LogfmtSchema(s)(v)
+		assert.NoError(err)
+		assert.Equal(`severity=WARN @timestamp=2019-10-22T16:50:17.123Z msg=warn err=failed stack_trace.0="main.main main.go:26"`, out)
+	})
+
+	t.Run("console", func(t *testing.T) {
+		out, err := ConsoleSchema(s, false)(v)
+		assert.NoError(err)
+		assert.Equal(`WARN  16:50:17.123 warn err=failed
+    main.main main.go:26`, out)
+	})
+}
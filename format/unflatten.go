@@ -0,0 +1,73 @@
+package format
+
+import (
+	"sort"
+	"strings"
+)
+
+// node is a map created by Unflatten, which is distinguished from the maps of the values.
+type node map[string]interface{}
+
+// Unflatten returns a new map that has the values of the keys joined with sep,
+// like "http.request.method", nested in maps, which reverses log.FlattenNested
+// for reading logs back.
+// The keys that can't be nested because of a conflict with another key,
+// like "http" and "http.method", or that have an empty part are left as they are.
+// v isn't modified.
+func Unflatten(v map[string]interface{}, sep string) map[string]interface{} {
+	if sep == "" {
+		return v
+	}
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	// the shorter keys come first so that the conflicts are resolved in the same way every time.
+	sort.Strings(keys)
+
+	root := make(node, len(v))
+	for _, k := range keys {
+		if !unflattenKey(root, strings.Split(k, sep), v[k]) {
+			root[k] = v[k]
+		}
+	}
+	return root.toMap()
+}
+
+// unflattenKey sets val at the path in n, and reports whether it is set.
+func unflattenKey(n node, path []string, val interface{}) bool {
+	for _, p := range path {
+		if p == "" {
+			return false
+		}
+	}
+	for _, p := range path[:len(path)-1] {
+		child, ok := n[p]
+		if !ok {
+			child = node{}
+			n[p] = child
+		}
+		if n, ok = child.(node); !ok {
+			return false
+		}
+	}
+	last := path[len(path)-1]
+	if _, ok := n[last]; ok {
+		return false
+	}
+	n[last] = val
+	return true
+}
+
+func (n node) toMap() map[string]interface{} {
+	m := make(map[string]interface{}, len(n))
+	for k, v := range n {
+		if child, ok := v.(node); ok {
+			m[k] = child.toMap()
+			continue
+		}
+		m[k] = v
+	}
+	return m
+}
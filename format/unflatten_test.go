@@ -0,0 +1,67 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnflatten(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("dotted keys are nested", func(t *testing.T) {
+		v := map[string]interface{}{
+			"level":               "INFO",
+			"http.request.method": "GET",
+			"http.request.path":   "/books",
+			"http.status":         200,
+		}
+		assert.Equal(map[string]interface{}{
+			"level": "INFO",
+			"http": map[string]interface{}{
+				"request": map[string]interface{}{
+					"method": "GET",
+					"path":   "/books",
+				},
+				"status": 200,
+			},
+		}, Unflatten(v, "."))
+		assert.Len(v, 4, "the argument isn't modified")
+	})
+
+	t.Run("conflicted keys are left as they are", func(t *testing.T) {
+		assert.Equal(map[string]interface{}{
+			"http":        "GET",
+			"http.method": "POST",
+			"a..b":        1,
+			"user": map[string]interface{}{
+				"id": "user1",
+			},
+			"user.id.x": 2,
+		}, Unflatten(map[string]interface{}{
+			"http":        "GET",
+			"http.method": "POST",
+			"a..b":        1,
+			"user.id":     "user1",
+			"user.id.x":   2,
+		}, "."))
+	})
+
+	t.Run("maps of the values aren't merged", func(t *testing.T) {
+		assert.Equal(map[string]interface{}{
+			"meta":      map[string]interface{}{"a": 1},
+			"meta.b":    2,
+			"trace_len": 3,
+		}, Unflatten(map[string]interface{}{
+			"meta":      map[string]interface{}{"a": 1},
+			"meta.b":    2,
+			"trace_len": 3,
+		}, "."))
+	})
+
+	t.Run("custom separator", func(t *testing.T) {
+		assert.Equal(map[string]interface{}{
+			"http": map[string]interface{}{"method": "GET"},
+		}, Unflatten(map[string]interface{}{"http_method": "GET"}, "_"))
+	})
+}
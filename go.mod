@@ -1,9 +1,15 @@
 module github.com/kyfk/log
 
-go 1.13
+go 1.21
 
 require (
 	github.com/pkg/errors v0.8.2-0.20190227000051-27936f6d90f9
 	github.com/stretchr/testify v1.4.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/lint v0.0.0-20190930215403-16217165b5de // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kyfk/log/level"
+)
+
+// Hook is fired on entries before they are formatted.
+// Hook can be used to enrich entries, send alerts, collect metrics and other more.
+type Hook interface {
+	// Levels returns the levels of entries the hook is fired on.
+	Levels() []level.Level
+	// Fire is called with an entry of one of the levels that Levels returns.
+	// Modifications of the entry are reflected in the output.
+	Fire(*Entry) error
+}
+
+func fireHooks(hs []Hook, e *Entry) {
+	for _, h := range hs {
+		if !hasLevel(h.Levels(), e.Level) {
+			continue
+		}
+		if err := h.Fire(e); err != nil {
+			// the error isn't logged by the logger so that hooks aren't fired recursively.
+			fmt.Fprintf(os.Stderr, "log: failed to fire hook: %v\n", err)
+		}
+	}
+}
+
+func hasLevel(lvs []level.Level, lv level.Level) bool {
+	for _, l := range lvs {
+		if l == lv {
+			return true
+		}
+	}
+	return false
+}
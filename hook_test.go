@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+type testHook struct {
+	levels  []level.Level
+	entries []*Entry
+	fire    func(*Entry) error
+}
+
+func (h *testHook) Levels() []level.Level { return h.levels }
+
+func (h *testHook) Fire(e *Entry) error {
+	h.entries = append(h.entries, e)
+	if h.fire != nil {
+		return h.fire(e)
+	}
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("hooks are fired on the entries of their levels", func(t *testing.T) {
+		hook := &testHook{levels: []level.Level{level.Warn, level.Error}}
+		logger := New(
+			Output(bytes.NewBuffer(nil)),
+			Hooks(hook),
+			Metadata(map[string]interface{}{"service": "book"}),
+			nowFunc(func() time.Time { return time.Time{} }),
+		)
+
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error(errors.New("error"))
+
+		if assert.Len(hook.entries, 2) {
+			assert.Equal(level.Warn, hook.entries[0].Level)
+			assert.Equal("warn", hook.entries[0].Message)
+			assert.Equal(map[string]interface{}{"service": "book"}, hook.entries[0].Metadata)
+			assert.NotNil(hook.entries[0].Trace)
+			assert.Equal(level.Error, hook.entries[1].Level)
+			assert.EqualError(hook.entries[1].Error, "error")
+		}
+	})
+
+	t.Run("modifications by hooks are output", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		hook := &testHook{
+			levels: []level.Level{level.Info},
+			fire: func(e *Entry) error {
+				e.Message = "modified"
+				e.Fields["host"] = "localhost"
+				e.Fields["level"] = "ignored"
+				return nil
+			},
+		}
+		logger := New(
+			Output(buf),
+			Format(format.JSON),
+			nowFunc(func() time.Time { return time.Time{} }),
+		)
+		logger.AddHooks(hook)
+
+		logger.Info("info")
+		assert.Equal(`{"host":"localhost","level":"INFO","message":"modified","meta":{},"time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("the entry is output even if the hook fails", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		hook := &testHook{
+			levels: []level.Level{level.Info},
+			fire:   func(e *Entry) error { return errors.New("failed") },
+		}
+		logger := New(Output(buf), Format(format.JSON), Hooks(hook))
+
+		logger.Info("info")
+		assert.Contains(buf.String(), `"message":"info"`)
+	})
+}
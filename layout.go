@@ -0,0 +1,49 @@
+package log
+
+import (
+	"io"
+
+	"github.com/kyfk/log/format"
+)
+
+// Layout builds the format of message output to each output of a logger
+// along the names of the fields that the logger outputs and the writer of the output.
+// It is set by FormatLayout or Sink.Layout and called whenever the configuration is changed,
+// so that the format follows FieldNames without setting the same schema to the format.
+type Layout interface {
+	Formatter(names format.Schema, w io.Writer) Formatter
+}
+
+// LayoutFunc is an adapter to use a function as Layout.
+type LayoutFunc func(names format.Schema, w io.Writer) Formatter
+
+// Formatter returns f(names, w).
+func (f LayoutFunc) Formatter(names format.Schema, w io.Writer) Formatter {
+	return f(names, w)
+}
+
+var (
+	// JSONLayout is the layout of format.JSON.
+	// The logger encodes the entries in the same way as format.JSON without reflection,
+	// unless it has hooks or it forwards the entries to slog.Handler.
+	JSONLayout Layout = jsonLayout{}
+
+	// LogfmtLayout is the layout of format.Logfmt that outputs the level, the time and the message
+	// of the names of the logger first.
+	LogfmtLayout Layout = LayoutFunc(func(names format.Schema, _ io.Writer) Formatter {
+		return format.LogfmtSchema(names)
+	})
+
+	// ConsoleLayout is the layout of format.Console that lays out the fields of the names of the logger
+	// and is colored if the output is a terminal and the NO_COLOR environment variable isn't set.
+	ConsoleLayout Layout = LayoutFunc(func(names format.Schema, w io.Writer) Formatter {
+		return format.ConsoleSchema(names, format.ColorEnabled(w))
+	})
+)
+
+// jsonLayout is the type of JSONLayout, which the logger finds to use the JSON encoder.
+type jsonLayout struct{}
+
+func (jsonLayout) Formatter(format.Schema, io.Writer) Formatter {
+	return format.JSON
+}
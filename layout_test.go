@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayout(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("NO_COLOR", "")
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(FormatLayout(ConsoleLayout), Output(buf), FieldNames(format.GCPSchema), withoutTrace(true))
+	logger.update(nowFunc(func() time.Time { return time.Time{} }))
+
+	t.Run("the layouts are built with the names and the output", func(t *testing.T) {
+		buf.Reset()
+		logger.Info("info")
+		assert.Equal("INFO  00:00:00.000 info\n", buf.String())
+
+		buf.Reset()
+		logger.SetFormatLayout(LogfmtLayout)
+		logger.Info("info")
+		assert.Equal("severity=INFO time=0001-01-01T00:00:00Z message=info\n", buf.String())
+
+		buf.Reset()
+		logger.SetFormatLayout(JSONLayout)
+		logger.Info("info")
+		assert.Equal(`{"message":"info","meta":{},"severity":"INFO","time":"0001-01-01T00:00:00Z"}`+"\n", buf.String())
+	})
+
+	t.Run("Format replaces the layout and is used as it is", func(t *testing.T) {
+		buf.Reset()
+		logger.SetFormat(format.ConsoleColor(true))
+		logger.Info("info")
+		assert.Contains(buf.String(), "\x1b[")
+		assert.False(logger.load().fastJSON)
+	})
+
+	t.Run("the layout of the sink is used instead of its formatter", func(t *testing.T) {
+		buf.Reset()
+		var names format.Schema
+		logger.SetOutputs(Sink{
+			Writer:    buf,
+			Formatter: format.JSON,
+			Layout: LayoutFunc(func(n format.Schema, w io.Writer) Formatter {
+				names = n
+				assert.Equal(buf, w)
+				return format.LogfmtSchema(n)
+			}),
+		})
+		logger.Info("info")
+		assert.Equal(format.GCPSchema, names)
+		assert.Equal("severity=INFO time=0001-01-01T00:00:00Z message=info\n", buf.String())
+	})
+}
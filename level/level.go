@@ -1,11 +1,21 @@
 package level
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
 // Level is used as minimum logging level.
 // If the minimum logging level is less than logging level that wanted to output,
 // the message isn't be outputted.
 type Level string
 
 const (
+	// Trace is trace level, which is more verbose than debug level.
+	Trace Level = "TRACE"
 	// Debug is debug level.
 	Debug Level = "DEBUG"
 	// Info is info level.
@@ -14,8 +24,85 @@ const (
 	Warn Level = "WARN"
 	// Error is error level.
 	Error Level = "ERROR"
+	// Panic is panic level.
+	Panic Level = "PANIC"
+	// Fatal is fatal level.
+	Fatal Level = "FATAL"
+)
+
+// custom holds the levels registered by Register as map[Level]int.
+// It is replaced as a whole on registration so that Priority doesn't lock.
+var (
+	custom   atomic.Value
+	customMu sync.Mutex
 )
 
+// customLevels returns the levels registered by Register, which is nil before the first registration.
+func customLevels() map[Level]int {
+	m, _ := custom.Load().(map[Level]int)
+	return m
+}
+
+// Register registers a custom level with priority.
+// The greater number is the higher priority, and the priorities of the built-in levels are
+// Trace 10, Debug 20, Info 30, Warn 40, Error 50, Panic 60 and Fatal 70.
+// For instance, Register("NOTICE", 35) registers a level between Info and Warn.
+// It returns an error if name is empty or already registered or priority isn't positive.
+// name can't be the name or the alias of a built-in level case-insensitively,
+// so that Parse never returns a custom level for them.
+func Register(name string, priority int) (Level, error) {
+	if name == "" {
+		return "", errors.New("level: the name of level is empty")
+	}
+	if lv, ok := builtin(name); ok {
+		return "", fmt.Errorf("level: level %s conflicts with the built-in level %s", name, lv)
+	}
+	if priority <= 0 {
+		return "", fmt.Errorf("level: the priority of level %s isn't positive: %d", name, priority)
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+
+	lv := Level(name)
+	if lv.Registered() {
+		return "", fmt.Errorf("level: level %s is already registered", name)
+	}
+
+	old := customLevels()
+	m := make(map[Level]int, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[lv] = priority
+	custom.Store(m)
+	return lv, nil
+}
+
+// MustRegister is like Register but panics if the level can't be registered.
+func MustRegister(name string, priority int) Level {
+	lv, err := Register(name, priority)
+	if err != nil {
+		panic(err)
+	}
+	return lv
+}
+
+// Levels returns all the registered levels in ascending order of priority.
+func Levels() []Level {
+	lvs := []Level{Trace, Debug, Info, Warn, Error, Panic, Fatal}
+	for lv := range customLevels() {
+		lvs = append(lvs, lv)
+	}
+	sort.SliceStable(lvs, func(i, j int) bool { return lvs[i].LessThan(lvs[j]) })
+	return lvs
+}
+
+// Registered reports whether the level is a built-in level or registered by Register.
+func (l Level) Registered() bool {
+	return l.Priority() > 0
+}
+
 // LessThan returns true if the level of receiver is less than the level of argument.
 func (l Level) LessThan(ll Level) bool {
 	return l.Priority() < ll.Priority()
@@ -23,17 +110,24 @@ func (l Level) LessThan(ll Level) bool {
 
 // Priority returns number of priorities.
 // The greater number is the higher priority.
+// The priority of a level that isn't registered is 0.
 func (l Level) Priority() int {
 	switch l {
+	case Trace:
+		return 10
 	case Debug:
-		return 1
+		return 20
 	case Info:
-		return 2
+		return 30
 	case Warn:
-		return 3
+		return 40
 	case Error:
-		return 4
+		return 50
+	case Panic:
+		return 60
+	case Fatal:
+		return 70
 	default:
-		return 9999
+		return customLevels()[l]
 	}
 }
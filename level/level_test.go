@@ -8,7 +8,62 @@ import (
 
 func TestLevel(t *testing.T) {
 	assert := assert.New(t)
+	assert.True(Trace.LessThan(Debug))
 	assert.True(Debug.LessThan(Info))
 	assert.True(Info.LessThan(Warn))
 	assert.True(Warn.LessThan(Error))
+	assert.True(Error.LessThan(Panic))
+	assert.True(Panic.LessThan(Fatal))
+}
+
+// the custom levels are registered once because they can't be unregistered.
+var (
+	testNotice = MustRegister("NOTICE", 35)
+	testAudit  = MustRegister("PARSE_AUDIT", 45)
+)
+
+func TestRegister(t *testing.T) {
+	assert := assert.New(t)
+
+	unknown := Level("UNKNOWN")
+	assert.False(unknown.Registered())
+	assert.Equal(0, unknown.Priority())
+
+	notice := testNotice
+	assert.Equal(Level("NOTICE"), notice)
+	assert.True(notice.Registered())
+	assert.True(Info.LessThan(notice))
+	assert.True(notice.LessThan(Warn))
+
+	// the other tests may register more levels, so only the relative order is checked.
+	index := func(lv Level) int {
+		for i, l := range Levels() {
+			if l == lv {
+				return i
+			}
+		}
+		return -1
+	}
+	order := []Level{Trace, Debug, Info, notice, Warn, Error, Panic, Fatal}
+	for i := 1; i < len(order); i++ {
+		assert.Less(index(order[i-1]), index(order[i]), order[i])
+	}
+
+	_, err := Register("NOTICE", 36)
+	assert.Error(err)
+	_, err = Register("INFO", 36)
+	assert.Error(err)
+	for _, name := range []string{"debug", "Info", "warning", "Err"} {
+		_, err = Register(name, 5)
+		assert.Error(err, name)
+	}
+	lv, err := Parse("DEBUG")
+	assert.NoError(err)
+	assert.Equal(Debug, lv)
+	_, err = Register("", 36)
+	assert.Error(err)
+	_, err = Register("ZERO", 0)
+	assert.Error(err)
+
+	assert.Panics(func() { MustRegister("NOTICE", 35) })
 }
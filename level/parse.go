@@ -0,0 +1,81 @@
+package level
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// aliases are the alternative names of the built-in levels accepted by Parse.
+var aliases = map[string]Level{
+	"WARNING":     Warn,
+	"ERR":         Error,
+	"INFORMATION": Info,
+}
+
+// Parse parses s as a registered level case-insensitively.
+// Some aliases like "warning" and "err" are accepted as well.
+func Parse(s string) (Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if name == "" {
+		return "", errors.New("level: empty level")
+	}
+	if lv, ok := builtin(name); ok {
+		return lv, nil
+	}
+	for _, lv := range Levels() {
+		if strings.ToUpper(string(lv)) == name {
+			return lv, nil
+		}
+	}
+	return "", fmt.Errorf("level: unknown level %q", s)
+}
+
+// builtin returns the built-in level whose name or alias is name case-insensitively.
+func builtin(name string) (Level, bool) {
+	name = strings.ToUpper(name)
+	if lv, ok := aliases[name]; ok {
+		return lv, true
+	}
+	switch lv := Level(name); lv {
+	case Trace, Debug, Info, Warn, Error, Panic, Fatal:
+		return lv, true
+	}
+	return "", false
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	return string(l)
+}
+
+// Set parses s and sets it to the level. Set and String implement flag.Value.
+func (l *Level) Set(s string) error {
+	lv, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*l = lv
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It is also used by the decoders of YAML, TOML and other more.
+func (l *Level) UnmarshalText(text []byte) error {
+	return l.Set(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("level: level must be a JSON string: %s", data)
+	}
+	return l.Set(s)
+}
@@ -0,0 +1,72 @@
+package level
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	for s, want := range map[string]Level{
+		"trace":   Trace,
+		"Debug":   Debug,
+		" INFO ":  Info,
+		"warn":    Warn,
+		"warning": Warn,
+		"err":     Error,
+		"error":   Error,
+		"panic":   Panic,
+		"fatal":   Fatal,
+	} {
+		lv, err := Parse(s)
+		assert.NoError(err, s)
+		assert.Equal(want, lv, s)
+	}
+
+	lv, err := Parse("parse_audit")
+	assert.NoError(err)
+	assert.Equal(testAudit, lv)
+
+	_, err = Parse("")
+	assert.Error(err)
+	_, err = Parse("extream")
+	assert.Error(err)
+}
+
+func TestFlag(t *testing.T) {
+	assert := assert.New(t)
+
+	lv := Info
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&lv, "level", "")
+
+	assert.NoError(fs.Parse([]string{"-level", "warning"}))
+	assert.Equal(Warn, lv)
+	assert.Equal("WARN", lv.String())
+
+	fs.SetOutput(io.Discard)
+	assert.Error(fs.Parse([]string{"-level", "extream"}))
+	assert.Equal(Warn, lv)
+}
+
+func TestJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var v struct {
+		Level Level `json:"level"`
+	}
+	assert.NoError(json.Unmarshal([]byte(`{"level":"debug"}`), &v))
+	assert.Equal(Debug, v.Level)
+
+	b, err := json.Marshal(v)
+	assert.NoError(err)
+	assert.Equal(`{"level":"DEBUG"}`, string(b))
+
+	assert.Error(json.Unmarshal([]byte(`{"level":"extream"}`), &v))
+	assert.Error(json.Unmarshal([]byte(`{"level":1}`), &v))
+}
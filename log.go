@@ -1,9 +1,11 @@
 package log
 
 import (
+	"context"
 	"io"
 	"log"
 
+	"github.com/kyfk/log/format"
 	"github.com/kyfk/log/level"
 )
 
@@ -11,12 +13,38 @@ var defaultLogger = New()
 
 // SetMinLevel sets minumum logging level to the default logger.
 func SetMinLevel(lv level.Level) {
-	defaultLogger.level = lv
+	defaultLogger.SetMinLevel(lv)
+}
+
+// SetLevelOverrides sets the minimum logging levels for the callers in specific packages to the default logger.
+// See LevelOverrides for the format of spec.
+func SetLevelOverrides(spec string) {
+	defaultLogger.SetLevelOverrides(spec)
+}
+
+// SetCaller sets the mode of the caller field to the default logger.
+func SetCaller(mode CallerMode) {
+	defaultLogger.SetCaller(mode)
+}
+
+// SetCallerSkip sets the number of frames skipped to find the caller to the default logger.
+func SetCallerSkip(n int) {
+	defaultLogger.SetCallerSkip(n)
+}
+
+// SetStackTraces sets the configuration of the stack traces to the default logger.
+func SetStackTraces(tc TraceConfig) {
+	defaultLogger.SetStackTraces(tc)
 }
 
 // SetFormat sets the format of message output to the default logger.
-func SetFormat(fm formatter) {
-	defaultLogger.formatter = fm
+func SetFormat(fm Formatter) {
+	defaultLogger.SetFormat(fm)
+}
+
+// SetFormatLayout sets the layout that builds the format of message output to the default logger.
+func SetFormatLayout(ly Layout) {
+	defaultLogger.SetFormatLayout(ly)
 }
 
 // SetMetadata sets metadata to default logger.
@@ -25,23 +53,63 @@ func SetFormat(fm formatter) {
 // set additional information to be able to search for conveniently.
 // For instance, HTTP Request ID, the id of user signed in, EC2 instance-id and other more.
 func SetMetadata(md map[string]interface{}) {
-	defaultLogger.metadata = md
+	defaultLogger.SetMetadata(md)
 }
 
 // SetOutput sets io.Writer as destination of logging message to the default logger.
 func SetOutput(out io.Writer) {
-	defaultLogger.logger = log.New(out, "", 0)
+	defaultLogger.SetOutput(out)
+}
+
+// SetOutputs sets sinks as destinations of logging message to the default logger.
+func SetOutputs(sinks ...Sink) {
+	defaultLogger.SetOutputs(sinks...)
 }
 
 // SetStdLogger sets StdLogger that is used output message to the default logger.
 func SetStdLogger(lg *log.Logger) {
-	defaultLogger.logger = lg
+	defaultLogger.SetStdLogger(lg)
+}
+
+// SetFieldNames sets the names of the keys of the fields that the default logger outputs for entries.
+func SetFieldNames(s format.Schema) {
+	defaultLogger.SetFieldNames(s)
 }
 
 // SetFlattenMetadata sets the flag if metadata is going to be flattened.
 // If the flag is put on, metadata is going to be flattened in output
 func SetFlattenMetadata(b bool) {
-	defaultLogger.flattenMetadata = b
+	defaultLogger.SetFlattenMetadata(b)
+}
+
+// SetFlattenNested sets the separator and the max depth of the keys of flattened nested metadata to the default logger.
+func SetFlattenNested(sep string, maxDepth int) {
+	defaultLogger.SetFlattenNested(sep, maxDepth)
+}
+
+// SetMetadataConflict sets the policy to resolve the conflicts of flattened metadata to the default logger.
+func SetMetadataConflict(p ConflictPolicy) {
+	defaultLogger.SetMetadataConflict(p)
+}
+
+// AddExitHooks adds functions that are called before the default logger exits the program in Fatal.
+func AddExitHooks(fs ...func()) {
+	defaultLogger.AddExitHooks(fs...)
+}
+
+// SetExitFunc sets the function that the default logger calls to exit the program in Fatal.
+func SetExitFunc(f func(int)) {
+	defaultLogger.SetExitFunc(f)
+}
+
+// With returns a new Logger derived from the default logger with metadata extended with fields.
+func With(fields map[string]interface{}) *Logger {
+	return defaultLogger.With(fields)
+}
+
+// Trace logs a message at level Trace on the default logger.
+func Trace(v ...interface{}) {
+	defaultLogger.Trace(v...)
 }
 
 // Debug logs a message at level Debug on the default logger.
@@ -63,3 +131,83 @@ func Warn(v ...interface{}) {
 func Error(err error) {
 	defaultLogger.Error(err)
 }
+
+// Errorf logs an error built from a formatted message at level Error on the default logger.
+func Errorf(format string, v ...interface{}) {
+	defaultLogger.Errorf(format, v...)
+}
+
+// ErrorMsg logs an error with a message at level Error on the default logger.
+func ErrorMsg(err error, msg string) {
+	defaultLogger.ErrorMsg(err, msg)
+}
+
+// ErrorWith logs an error with fields at level Error on the default logger.
+func ErrorWith(err error, fields map[string]interface{}) {
+	defaultLogger.ErrorWith(err, fields)
+}
+
+// Panic logs a message at level Panic on the default logger, then panics with the message.
+func Panic(v ...interface{}) {
+	defaultLogger.Panic(v...)
+}
+
+// Panicf logs a formatted message at level Panic on the default logger, then panics with the message.
+func Panicf(format string, v ...interface{}) {
+	defaultLogger.Panicf(format, v...)
+}
+
+// Fatal logs a message at level Fatal on the default logger, then exits the program.
+func Fatal(v ...interface{}) {
+	defaultLogger.Fatal(v...)
+}
+
+// Fatalf logs a formatted message at level Fatal on the default logger, then exits the program.
+func Fatalf(format string, v ...interface{}) {
+	defaultLogger.Fatalf(format, v...)
+}
+
+// Log logs a message at level lv on the default logger.
+func Log(lv level.Level, v ...interface{}) {
+	defaultLogger.Log(lv, v...)
+}
+
+// Debugw logs a message with fields at level Debug on the default logger.
+func Debugw(msg string, keysAndValues ...interface{}) {
+	defaultLogger.Debugw(msg, keysAndValues...)
+}
+
+// Infow logs a message with fields at level Info on the default logger.
+func Infow(msg string, keysAndValues ...interface{}) {
+	defaultLogger.Infow(msg, keysAndValues...)
+}
+
+// Warnw logs a message with fields at level Warn on the default logger.
+func Warnw(msg string, keysAndValues ...interface{}) {
+	defaultLogger.Warnw(msg, keysAndValues...)
+}
+
+// Errorw logs an error with fields at level Error on the default logger.
+func Errorw(err error, keysAndValues ...interface{}) {
+	defaultLogger.Errorw(err, keysAndValues...)
+}
+
+// DebugContext logs a message at level Debug on the logger that ctx carries.
+func DebugContext(ctx context.Context, v ...interface{}) {
+	FromContext(ctx).DebugContext(ctx, v...)
+}
+
+// InfoContext logs a message at level Info on the logger that ctx carries.
+func InfoContext(ctx context.Context, v ...interface{}) {
+	FromContext(ctx).InfoContext(ctx, v...)
+}
+
+// WarnContext logs a message at level Warn on the logger that ctx carries.
+func WarnContext(ctx context.Context, v ...interface{}) {
+	FromContext(ctx).WarnContext(ctx, v...)
+}
+
+// ErrorContext logs a message at level Error on the logger that ctx carries.
+func ErrorContext(ctx context.Context, err error) {
+	FromContext(ctx).ErrorContext(ctx, err)
+}
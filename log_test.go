@@ -16,48 +16,48 @@ import (
 
 func TestSetMinLevel(t *testing.T) {
 	SetMinLevel(level.Debug)
-	assert.Equal(t, level.Debug, defaultLogger.level)
+	assert.Equal(t, level.Debug, defaultLogger.load().level)
 	SetMinLevel(level.Warn)
-	assert.Equal(t, level.Warn, defaultLogger.level)
+	assert.Equal(t, level.Warn, defaultLogger.load().level)
 }
 
 func TestSetFormat(t *testing.T) {
 	SetFormat(format.JSON)
 	f1 := runtime.FuncForPC(reflect.ValueOf(format.JSON).Pointer()).Name()
-	f2 := runtime.FuncForPC(reflect.ValueOf(defaultLogger.formatter).Pointer()).Name()
+	f2 := runtime.FuncForPC(reflect.ValueOf(defaultLogger.load().formatter).Pointer()).Name()
 	assert.Equal(t, f1, f2)
 
 	SetFormat(format.JSONPretty)
 	f3 := runtime.FuncForPC(reflect.ValueOf(format.JSONPretty).Pointer()).Name()
-	f4 := runtime.FuncForPC(reflect.ValueOf(defaultLogger.formatter).Pointer()).Name()
+	f4 := runtime.FuncForPC(reflect.ValueOf(defaultLogger.load().formatter).Pointer()).Name()
 	assert.Equal(t, f3, f4)
 }
 
 func TestSetMetadata(t *testing.T) {
 	var meta1 = map[string]interface{}{"meta1": "meta1"}
 	SetMetadata(meta1)
-	assert.Equal(t, meta1, defaultLogger.metadata)
+	assert.Equal(t, meta1, defaultLogger.load().metadata)
 	var meta2 = map[string]interface{}{"meta2": "meta2"}
 	SetMetadata(meta2)
-	assert.Equal(t, meta2, defaultLogger.metadata)
+	assert.Equal(t, meta2, defaultLogger.load().metadata)
 }
 
 func TestSetStdLogger(t *testing.T) {
 	lg := log.New(os.Stdout, "", 0)
 	SetStdLogger(lg)
-	assert.Equal(t, lg, defaultLogger.logger)
+	assert.Equal(t, lg, defaultLogger.load().sinks[0].out)
 
 	lg1 := log.New(os.Stdout, "", 0)
 	SetStdLogger(lg1)
-	assert.Equal(t, lg1, defaultLogger.logger)
+	assert.Equal(t, lg1, defaultLogger.load().sinks[0].out)
 }
 
 func TestSetFlattenMetadata(t *testing.T) {
 	SetFlattenMetadata(true)
-	assert.Equal(t, true, defaultLogger.flattenMetadata)
+	assert.Equal(t, true, defaultLogger.load().flattenMetadata)
 
 	SetFlattenMetadata(false)
-	assert.Equal(t, false, defaultLogger.flattenMetadata)
+	assert.Equal(t, false, defaultLogger.load().flattenMetadata)
 }
 
 func ExampleOutput() {
@@ -70,8 +70,8 @@ func ExampleOutput() {
 	SetFlattenMetadata(true)
 	SetOutput(os.Stdout)
 
-	defaultLogger.nowFunc = func() time.Time { return time.Time{} }
-	defaultLogger.withoutTrace = true
+	defaultLogger.update(nowFunc(func() time.Time { return time.Time{} }))
+	defaultLogger.update(withoutTrace(true))
 
 	Debug("debug")
 	Info("info")
@@ -102,7 +102,12 @@ func ExampleOutput() {
 	//   "uesr_id": "86f32b8b-ec0d-479f-aed1-1070aa54cecf"
 	// }
 	// {
-	//   "error": "*errors.errorString: error",
+	//   "error": [
+	//     {
+	//       "message": "error",
+	//       "type": "*errors.errorString"
+	//     }
+	//   ],
 	//   "level": "ERROR",
 	//   "request_id": "943ad105-7543-11e6-a9ac-65e093327849",
 	//   "time": "0001-01-01T00:00:00Z",
@@ -2,9 +2,13 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
-	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kyfk/log/format"
@@ -12,208 +16,1088 @@ import (
 	"github.com/pkg/errors"
 )
 
-type formatter func(map[string]interface{}) (string, error)
+// Formatter formats the fields of an entry into the message output.
+type Formatter func(map[string]interface{}) (string, error)
 
-// Logger has fields that Option or setter SetXXXX set.
-type Logger struct {
-	level           level.Level
-	logger          *log.Logger
-	formatter       formatter
-	metadata        map[string]interface{}
-	flattenMetadata bool
-	isMergeFailed   bool
-	isFormatFailed  bool
+// config is a snapshot of the configuration of Logger.
+// A config is never modified once it is stored into Logger,
+// Option and the setters build a new one and swap it atomically.
+type config struct {
+	level            level.Level
+	sinks            []sink
+	formatter        Formatter
+	layout           Layout // used instead of formatter if it is set
+	metadata         map[string]interface{}
+	names            format.Schema
+	flattenMetadata  bool
+	flattenSep       string
+	flattenDepth     int
+	metadataConflict ConflictPolicy
+	hooks            []Hook
+	async            *asyncWriter
+	slogHandler      slog.Handler
+	exitHooks        []func()
+	exitFunc         func(int)
+	overrides        *levelOverrides
+	callerMode       CallerMode
+	callerSkip       int
+	trace            TraceConfig
+
+	// these fields are derived from the fields above by prepare.
+	flatMetadata     map[string]interface{} // metadata output at the top level if it is flattened
+	metadataKeys     []string               // sorted keys of the metadata output
+	conflictPrefix   string                 // prefix of the keys renamed by ConflictRename
+	fastJSON         bool
+	conflictReported *int32 // set to 1 when the conflict of metadata is reported
 
 	// these fields are only for testing
 	nowFunc      func() time.Time
 	withoutTrace bool
 }
 
+// Logger outputs logging messages along its configuration.
+// Logger is safe for concurrent use by multiple goroutines.
+type Logger struct {
+	config         atomic.Value // *config
+	mu             sync.Mutex   // serializes updates of config
+	revert         levelRevert
+	isFormatFailed int32
+
+	// root and layer are set if the logger is derived by With.
+	// The configuration of a derived logger is the one of root
+	// with its own layer on top, which is cached in derived.
+	root    *Logger
+	layer   atomic.Value // *derivedLayer
+	derived atomic.Value // *derivedConfig
+}
+
+// derivedLayer is what a derived logger has on top of the configuration of its root.
+type derivedLayer struct {
+	metadata map[string]interface{}
+	ops      []Option // the options set by the setters of the metadata and the field names
+}
+
+// derivedConfig is the configuration of a derived logger built from base and layer.
+type derivedConfig struct {
+	base  *config
+	layer *derivedLayer
+	c     *config
+}
+
 // New initialize new Logger with options.
 func New(ops ...Option) *Logger {
-	lg := Logger{
+	c := config{
 		level:     level.Debug,
-		logger:    log.New(os.Stdout, "", 0),
+		sinks:     []sink{{out: log.New(os.Stdout, "", 0)}},
 		formatter: format.JSONPretty,
 		metadata:  map[string]interface{}{},
+		exitFunc:  os.Exit,
 		nowFunc:   time.Now,
 	}
 
 	for _, o := range ops {
-		lg = o(lg)
+		c = o(c)
+	}
+	return newLogger(c)
+}
+
+func newLogger(c config) *Logger {
+	c.prepare()
+	lg := &Logger{}
+	lg.config.Store(&c)
+	return lg
+}
+
+// enabled reports whether lv is enabled for the caller of the logger.
+// The caller is looked up from the stack only if the level overrides are set.
+func (c *config) enabled(lv level.Level) bool {
+	if c.overrides == nil {
+		return !lv.LessThan(c.level)
+	}
+	return c.overrides.enabled(lv, c.level, c.callerSkip)
+}
+
+// prepare sets the fields derived from the configuration.
+func (c *config) prepare() {
+	c.conflictReported = new(int32)
+	c.names = c.names.WithDefaults()
+	c.prepareMetadata()
+
+	// the sinks are copied because the old configuration shares them.
+	sinks := make([]sink, len(c.sinks))
+	c.fastJSON = len(c.sinks) > 0 && c.slogHandler == nil
+	for i, sk := range c.sinks {
+		var isJSON bool
+		sk.format, isJSON = c.resolveFormat(sk)
+		if !isJSON {
+			c.fastJSON = false
+		}
+		sinks[i] = sk
+	}
+	c.sinks = sinks
+}
+
+// prepareMetadata sets the fields derived from the metadata.
+func (c *config) prepareMetadata() {
+	md := c.metadata
+	c.flatMetadata = nil
+	if c.flattenMetadata {
+		if c.flattenSep != "" {
+			md = flattenNested(md, c.flattenSep, c.flattenDepth)
+		}
+		c.flatMetadata = md
+	}
+	c.conflictPrefix = c.names.Metadata + defaultFlattenSeparator
+	if c.flattenSep != "" {
+		c.conflictPrefix = c.names.Metadata + c.flattenSep
+	}
+
+	c.metadataKeys = make([]string, 0, len(md))
+	for k := range md {
+		c.metadataKeys = append(c.metadataKeys, k)
+	}
+	sort.Strings(c.metadataKeys)
+}
+
+// withMetadata returns a copy of the configuration whose metadata is extended with fields.
+// The conflict of the metadata is reported once for both of them.
+func (c *config) withMetadata(fields map[string]interface{}) *config {
+	d := *c
+	d.metadata = extend(c.metadata, fields)
+	d.prepareMetadata()
+	return &d
+}
+
+// load returns the current snapshot of the configuration.
+// The snapshot of a derived logger is rebuilt only when the one of its root or its layer is changed.
+func (l *Logger) load() *config {
+	if l.root == nil {
+		return l.config.Load().(*config)
+	}
+	base := l.root.load()
+	ly := l.layer.Load().(*derivedLayer)
+	if d, ok := l.derived.Load().(*derivedConfig); ok && d.base == base && d.layer == ly {
+		return d.c
+	}
+	c := base.withLayer(ly)
+	l.derived.Store(&derivedConfig{base: base, layer: ly, c: c})
+	return c
+}
+
+// withLayer returns a copy of the configuration with the layer of a derived logger on top.
+func (c *config) withLayer(ly *derivedLayer) *config {
+	if len(ly.ops) == 0 {
+		return c.withMetadata(ly.metadata)
+	}
+	d := *c
+	for _, o := range ly.ops {
+		d = o(d)
+	}
+	d.metadata = extend(d.metadata, ly.metadata)
+	d.prepare()
+	return &d
+}
+
+// update applies options to a copy of the current configuration and swaps it.
+// The options to a derived logger are applied to its root.
+func (l *Logger) update(ops ...Option) {
+	if l.root != nil {
+		l.root.update(ops...)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := *l.load()
+	for _, o := range ops {
+		c = o(c)
 	}
-	return &lg
+	c.prepare()
+	l.config.Store(&c)
+}
+
+// SetMinLevel sets minumum logging level to a logger.
+// If lv isn't registered, the minimum logging level isn't changed and it is written to stderr.
+// It cancels the revert of the level set by SetMinLevelFor.
+func (l *Logger) SetMinLevel(lv level.Level) {
+	l.SetMinLevelFor(lv, 0)
+}
+
+// SetLevelOverrides sets the minimum logging levels for the callers in specific packages to a logger.
+// See LevelOverrides for the format of spec.
+func (l *Logger) SetLevelOverrides(spec string) {
+	l.update(LevelOverrides(spec))
+}
+
+// SetCaller sets the mode of the caller field to a logger.
+func (l *Logger) SetCaller(mode CallerMode) {
+	l.update(Caller(mode))
+}
+
+// SetCallerSkip sets the number of frames skipped to find the caller to a logger.
+func (l *Logger) SetCallerSkip(n int) {
+	l.update(CallerSkip(n))
+}
+
+// SetStackTraces sets the configuration of the stack traces to a logger.
+func (l *Logger) SetStackTraces(tc TraceConfig) {
+	l.update(StackTraces(tc))
+}
+
+// Level returns minumum logging level of a logger.
+func (l *Logger) Level() level.Level {
+	return l.load().level
+}
+
+// SetFormat sets the format of message output to a logger.
+func (l *Logger) SetFormat(fm Formatter) {
+	l.update(Format(fm))
+}
+
+// SetFormatLayout sets the layout that builds the format of message output to a logger.
+func (l *Logger) SetFormatLayout(ly Layout) {
+	l.update(FormatLayout(ly))
 }
 
 // SetMetadata sets a metadata to a logger.
+// On a logger derived by With, it replaces only the fields of the logger,
+// which extend the metadata of the original logger.
 func (l *Logger) SetMetadata(meta map[string]interface{}) {
-	l.metadata = meta
+	if l.root != nil {
+		l.updateLayer(func(ly *derivedLayer) { ly.metadata = extend(meta, nil) })
+		return
+	}
+	l.update(Metadata(meta))
+}
+
+// SetOutput sets io.Writer as destination of logging message to a logger.
+func (l *Logger) SetOutput(out io.Writer) {
+	l.update(Output(out))
+}
+
+// SetOutputs sets sinks as destinations of logging message to a logger.
+func (l *Logger) SetOutputs(sinks ...Sink) {
+	l.update(Outputs(sinks...))
+}
+
+// SetStdLogger sets StdLogger that is used output message to a logger.
+func (l *Logger) SetStdLogger(lg *log.Logger) {
+	l.update(StdLogger(lg))
+}
+
+// SetFieldNames sets the names of the keys of the fields that a logger outputs for entries.
+// On a logger derived by With, it changes only the logger.
+func (l *Logger) SetFieldNames(s format.Schema) {
+	if err := s.Validate(); err != nil {
+		// reported here so that it isn't reported each time a derived logger is rebuilt.
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	l.updateOwn(FieldNames(s))
+}
+
+// SetFlattenMetadata sets the flag if metadata is going to be flattened.
+// On a logger derived by With, it changes only the logger.
+func (l *Logger) SetFlattenMetadata(b bool) {
+	l.updateOwn(FlattenMetadata(b))
+}
+
+// SetFlattenNested sets the separator and the max depth of the keys of flattened nested metadata to a logger.
+// On a logger derived by With, it changes only the logger.
+func (l *Logger) SetFlattenNested(sep string, maxDepth int) {
+	l.updateOwn(FlattenNested(sep, maxDepth))
+}
+
+// updateOwn applies options to the logger.
+// Unlike update, the options to a derived logger are added to its own layer.
+func (l *Logger) updateOwn(ops ...Option) {
+	if l.root == nil {
+		l.update(ops...)
+		return
+	}
+	l.updateLayer(func(ly *derivedLayer) {
+		ly.ops = append(ly.ops[:len(ly.ops):len(ly.ops)], ops...)
+	})
+}
+
+// updateLayer applies f to a copy of the layer of a derived logger and swaps it.
+func (l *Logger) updateLayer(f func(*derivedLayer)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ly := *l.layer.Load().(*derivedLayer)
+	f(&ly)
+	l.layer.Store(&ly)
+}
+
+// SetMetadataConflict sets the policy to resolve the conflicts of flattened metadata to a logger.
+func (l *Logger) SetMetadataConflict(p ConflictPolicy) {
+	l.update(MetadataConflict(p))
+}
+
+// AddHooks adds hooks to a logger.
+func (l *Logger) AddHooks(hs ...Hook) {
+	l.update(Hooks(hs...))
+}
+
+// AddExitHooks adds functions that are called before a logger exits the program in Fatal.
+func (l *Logger) AddExitHooks(fs ...func()) {
+	l.update(ExitHooks(fs...))
+}
+
+// SetExitFunc sets the function that a logger calls to exit the program in Fatal.
+func (l *Logger) SetExitFunc(f func(int)) {
+	l.update(ExitFunc(f))
+}
+
+// Flush waits until all the messages buffered by an asynchronous logger are written.
+// If the logger isn't asynchronous, Flush does nothing.
+func (l *Logger) Flush() {
+	if c := l.load(); c.async != nil {
+		c.async.flush()
+	}
+}
+
+// Close writes all the messages buffered by an asynchronous logger
+// and stops its background goroutine.
+// Messages logged after Close are written synchronously.
+// If the logger isn't asynchronous, Close does nothing.
+func (l *Logger) Close() error {
+	if c := l.load(); c.async != nil {
+		c.async.close()
+	}
+	return nil
+}
+
+// Dropped returns the number of messages an asynchronous logger has dropped
+// along its overflow policy.
+func (l *Logger) Dropped() uint64 {
+	if c := l.load(); c.async != nil {
+		return atomic.LoadUint64(&c.async.dropped)
+	}
+	return 0
+}
+
+// With returns a new Logger derived from the logger.
+// The new logger shares the configuration with the logger, including the changes after With,
+// and carries the metadata of the logger extended with fields.
+// If a key of fields is already in the metadata, the value of fields is used.
+// The setters of the new logger change the shared configuration, so that they change the logger as well,
+// except SetMetadata, SetFieldNames, SetFlattenMetadata and SetFlattenNested, which change only the new logger.
+func (l *Logger) With(fields map[string]interface{}) *Logger {
+	root, ly := l, &derivedLayer{metadata: extend(fields, nil)}
+	if l.root != nil {
+		parent := l.layer.Load().(*derivedLayer)
+		root, ly = l.root, &derivedLayer{metadata: extend(parent.metadata, fields), ops: parent.ops}
+	}
+	d := &Logger{root: root}
+	d.layer.Store(ly)
+	return d
+}
+
+// Trace logs a message at level Trace.
+func (l *Logger) Trace(v ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Trace) || len(v) == 0 {
+		return
+	}
+	l.output(c, &Entry{
+		Level:   level.Trace,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
+	})
+}
+
+// Tracef logs a formatted message at level Trace.
+func (l *Logger) Tracef(format string, v ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Trace) || len(v) == 0 {
+		return
+	}
+	l.output(c, &Entry{
+		Level:   level.Trace,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprintf(format, v...),
+	})
+}
+
+// Log logs a message at level lv, which is typically registered by level.Register.
+// Nothing is logged if lv isn't registered.
+// The stack trace is added if lv is Warn or higher, but Log neither panics nor exits
+// even if lv is Panic or Fatal.
+func (l *Logger) Log(lv level.Level, v ...interface{}) {
+	c := l.load()
+	if !lv.Registered() || !c.enabled(lv) || len(v) == 0 {
+		return
+	}
+
+	e := &Entry{
+		Level:   lv,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
+	}
+
+	if st, ok := stackTraceOf(v[0]); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// Logf logs a formatted message at level lv.
+// See Log for the details.
+func (l *Logger) Logf(lv level.Level, format string, v ...interface{}) {
+	c := l.load()
+	if !lv.Registered() || !c.enabled(lv) || len(v) == 0 {
+		return
+	}
+
+	e := &Entry{
+		Level:   lv,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprintf(format, v...),
+	}
+
+	if st, ok := stackTraceOf(v[0]); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
 }
 
 // Debug logs a message at level Debug.
 func (l *Logger) Debug(v ...interface{}) {
-	if level.Debug.LessThan(l.level) || len(v) == 0 {
+	c := l.load()
+	if !c.enabled(level.Debug) || len(v) == 0 {
 		return
 	}
-	l.println(map[string]interface{}{
-		"level":   level.Debug,
-		"message": fmt.Sprint(v...),
-		"time":    l.nowFunc(),
+	l.output(c, &Entry{
+		Level:   level.Debug,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
 	})
 }
 
 // Debugf logs a formatted message at level Debug.
 func (l *Logger) Debugf(format string, v ...interface{}) {
-	if level.Debug.LessThan(l.level) || len(v) == 0 {
+	c := l.load()
+	if !c.enabled(level.Debug) || len(v) == 0 {
 		return
 	}
-	l.println(map[string]interface{}{
-		"level":   level.Debug,
-		"message": fmt.Sprintf(format, v...),
-		"time":    l.nowFunc(),
+	l.output(c, &Entry{
+		Level:   level.Debug,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprintf(format, v...),
 	})
 }
 
 // Info logs a message at level Info.
 func (l *Logger) Info(v ...interface{}) {
-	if level.Info.LessThan(l.level) || len(v) == 0 {
+	c := l.load()
+	if !c.enabled(level.Info) || len(v) == 0 {
 		return
 	}
-	l.println(map[string]interface{}{
-		"level":   level.Info,
-		"message": fmt.Sprint(v...),
-		"time":    l.nowFunc(),
+	l.output(c, &Entry{
+		Level:   level.Info,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
 	})
 }
 
 // Infof logs a formatted message at level Info.
 func (l *Logger) Infof(format string, v ...interface{}) {
-	if level.Info.LessThan(l.level) || len(v) == 0 {
+	c := l.load()
+	if !c.enabled(level.Info) || len(v) == 0 {
 		return
 	}
-	l.println(map[string]interface{}{
-		"level":   level.Info,
-		"message": fmt.Sprintf(format, v...),
-		"time":    l.nowFunc(),
+	l.output(c, &Entry{
+		Level:   level.Info,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprintf(format, v...),
 	})
 }
 
 // Warn logs a message at level Warn.
 func (l *Logger) Warn(v ...interface{}) {
-	if level.Warn.LessThan(l.level) || len(v) == 0 {
+	c := l.load()
+	if !c.enabled(level.Warn) || len(v) == 0 {
 		return
 	}
 
-	data := map[string]interface{}{
-		"level": level.Warn,
-		"time":  l.nowFunc(),
+	e := &Entry{
+		Level:   level.Warn,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprint(v...),
 	}
 
-	switch v0 := v[0].(type) {
-	case interface{ StackTrace() errors.StackTrace }:
-		if !l.withoutTrace {
-			data["trace"] = v0.StackTrace()
-		}
-	default:
-		if !l.withoutTrace {
-			data["trace"] = callers().framesString()
-		}
+	if st, ok := stackTraceOf(v[0]); ok {
+		e.stack = st
 	}
 
-	data["message"] = fmt.Sprint(v...)
-
-	l.println(data)
+	l.output(c, e)
 }
 
-// Warn logs a formatted message at level Warn.
+// Warnf logs a formatted message at level Warn.
 func (l *Logger) Warnf(format string, v ...interface{}) {
-	if level.Warn.LessThan(l.level) || len(v) == 0 {
+	c := l.load()
+	if !c.enabled(level.Warn) || len(v) == 0 {
+		return
+	}
+
+	e := &Entry{
+		Level:   level.Warn,
+		Time:    c.nowFunc(),
+		Message: fmt.Sprintf(format, v...),
+	}
+
+	if st, ok := stackTraceOf(v[0]); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// Error logs a message at level Error.
+func (l *Logger) Error(err error) {
+	c := l.load()
+	if !c.enabled(level.Error) || err == nil {
 		return
 	}
 
-	data := map[string]interface{}{
-		"level": level.Warn,
-		"time":  l.nowFunc(),
+	e := &Entry{
+		Level: level.Error,
+		Time:  c.nowFunc(),
+		Error: err,
 	}
 
-	switch v0 := v[0].(type) {
-	case interface{ StackTrace() errors.StackTrace }:
-		if !l.withoutTrace {
-			data["trace"] = v0.StackTrace()
+	if st, ok := stackTraceOf(err); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// Errorf logs an error built from a formatted message at level Error.
+// The error has the stack trace where Errorf is called, captured in the same way as the logger,
+// and it wraps the errors of the %w verbs like fmt.Errorf.
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Error) {
+		return
+	}
+
+	err := &stackError{error: fmt.Errorf(format, v...), stack: c.capturePCs(0)}
+	e := &Entry{
+		Level: level.Error,
+		Time:  c.nowFunc(),
+		Error: err,
+	}
+	if st, ok := stackTraceOf(err); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// ErrorMsg logs an error with a message at level Error.
+func (l *Logger) ErrorMsg(err error, msg string) {
+	c := l.load()
+	if !c.enabled(level.Error) || err == nil {
+		return
+	}
+
+	e := &Entry{
+		Level:   level.Error,
+		Time:    c.nowFunc(),
+		Message: msg,
+		Error:   err,
+	}
+
+	if st, ok := stackTraceOf(err); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// ErrorWith logs an error with fields at level Error.
+func (l *Logger) ErrorWith(err error, fields map[string]interface{}) {
+	c := l.load()
+	if !c.enabled(level.Error) || err == nil {
+		return
+	}
+
+	e := &Entry{
+		Level:  level.Error,
+		Time:   c.nowFunc(),
+		Error:  err,
+		Fields: extend(fields, nil),
+	}
+
+	if st, ok := stackTraceOf(err); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// Panic logs a message at level Panic, then panics with the message.
+// Before panicking, the buffered messages are flushed.
+func (l *Logger) Panic(v ...interface{}) {
+	c := l.load()
+	msg := fmt.Sprint(v...)
+	if c.enabled(level.Panic) {
+		e := &Entry{
+			Level:   level.Panic,
+			Time:    c.nowFunc(),
+			Message: msg,
+		}
+		if st, ok := stackTraceOf(firstOf(v)); ok {
+			e.stack = st
+		}
+		l.output(c, e)
+	}
+	l.Flush()
+	panic(msg)
+}
+
+// Panicf logs a formatted message at level Panic, then panics with the message.
+// Before panicking, the buffered messages are flushed.
+func (l *Logger) Panicf(format string, v ...interface{}) {
+	c := l.load()
+	msg := fmt.Sprintf(format, v...)
+	if c.enabled(level.Panic) {
+		e := &Entry{
+			Level:   level.Panic,
+			Time:    c.nowFunc(),
+			Message: msg,
+		}
+		if st, ok := stackTraceOf(firstOf(v)); ok {
+			e.stack = st
+		}
+		l.output(c, e)
+	}
+	l.Flush()
+	panic(msg)
+}
+
+// Fatal logs a message at level Fatal, then exits the program.
+// Before exiting, the buffered messages are flushed and the exit hooks are called.
+// The program exits with os.Exit(1) unless the exit function is replaced with ExitFunc.
+func (l *Logger) Fatal(v ...interface{}) {
+	c := l.load()
+	if c.enabled(level.Fatal) {
+		e := &Entry{
+			Level:   level.Fatal,
+			Time:    c.nowFunc(),
+			Message: fmt.Sprint(v...),
 		}
-	default:
-		if !l.withoutTrace {
-			data["trace"] = callers().framesString()
+		if st, ok := stackTraceOf(firstOf(v)); ok {
+			e.stack = st
 		}
+		l.output(c, e)
 	}
+	l.exit(c)
+}
 
-	data["message"] = fmt.Sprintf(format, v...)
+// Fatalf logs a formatted message at level Fatal, then exits the program.
+// Before exiting, the buffered messages are flushed and the exit hooks are called.
+// The program exits with os.Exit(1) unless the exit function is replaced with ExitFunc.
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	c := l.load()
+	if c.enabled(level.Fatal) {
+		e := &Entry{
+			Level:   level.Fatal,
+			Time:    c.nowFunc(),
+			Message: fmt.Sprintf(format, v...),
+		}
+		if st, ok := stackTraceOf(firstOf(v)); ok {
+			e.stack = st
+		}
+		l.output(c, e)
+	}
+	l.exit(c)
+}
 
-	l.println(data)
+// exit flushes the logger, calls the exit hooks and exits the program.
+func (l *Logger) exit(c *config) {
+	l.Flush()
+	for _, f := range c.exitHooks {
+		f()
+	}
+	c.exitFunc(1)
 }
 
-// Error logs a message at level Error.
-func (l *Logger) Error(err error) {
-	if level.Error.LessThan(l.level) || err == nil {
+func firstOf(v []interface{}) interface{} {
+	if len(v) == 0 {
+		return nil
+	}
+	return v[0]
+}
+
+// Debugw logs a message with fields at level Debug.
+// The fields are the alternating keys and values of keysAndValues.
+func (l *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Debug) {
 		return
 	}
+	l.output(c, &Entry{
+		Level:   level.Debug,
+		Time:    c.nowFunc(),
+		Message: msg,
+		Fields:  keysAndValuesToFields(keysAndValues),
+	})
+}
 
-	data := map[string]interface{}{
-		"level": level.Error,
-		"time":  l.nowFunc(),
+// Infow logs a message with fields at level Info.
+// The fields are the alternating keys and values of keysAndValues.
+func (l *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Info) {
+		return
 	}
+	l.output(c, &Entry{
+		Level:   level.Info,
+		Time:    c.nowFunc(),
+		Message: msg,
+		Fields:  keysAndValuesToFields(keysAndValues),
+	})
+}
 
-	if !l.withoutTrace {
-		switch v := err.(type) {
-		case interface{ StackTrace() errors.StackTrace }:
-			data["trace"] = v.StackTrace()
-		default:
-			data["trace"] = callers().framesString()
+// Warnw logs a message with fields at level Warn.
+// The fields are the alternating keys and values of keysAndValues.
+func (l *Logger) Warnw(msg string, keysAndValues ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Warn) {
+		return
+	}
+
+	e := &Entry{
+		Level:   level.Warn,
+		Time:    c.nowFunc(),
+		Message: msg,
+		Fields:  keysAndValuesToFields(keysAndValues),
+	}
+	l.output(c, e)
+}
+
+// Errorw logs an error with fields at level Error.
+// The fields are the alternating keys and values of keysAndValues.
+func (l *Logger) Errorw(err error, keysAndValues ...interface{}) {
+	c := l.load()
+	if !c.enabled(level.Error) || err == nil {
+		return
+	}
+
+	e := &Entry{
+		Level:  level.Error,
+		Time:   c.nowFunc(),
+		Error:  err,
+		Fields: keysAndValuesToFields(keysAndValues),
+	}
+
+	if st, ok := stackTraceOf(err); ok {
+		e.stack = st
+	}
+
+	l.output(c, e)
+}
+
+// DebugFields logs a message with typed fields at level Debug.
+// If the level is disabled, it doesn't allocate.
+func (l *Logger) DebugFields(msg string, fields ...Field) {
+	c := l.load()
+	if !c.enabled(level.Debug) {
+		return
+	}
+	l.outputFields(c, Entry{
+		Level:   level.Debug,
+		Time:    c.nowFunc(),
+		Message: msg,
+	}, fields)
+}
+
+// InfoFields logs a message with typed fields at level Info.
+// If the level is disabled, it doesn't allocate.
+func (l *Logger) InfoFields(msg string, fields ...Field) {
+	c := l.load()
+	if !c.enabled(level.Info) {
+		return
+	}
+	l.outputFields(c, Entry{
+		Level:   level.Info,
+		Time:    c.nowFunc(),
+		Message: msg,
+	}, fields)
+}
+
+// WarnFields logs a message with typed fields at level Warn.
+// If the level is disabled, it doesn't allocate.
+func (l *Logger) WarnFields(msg string, fields ...Field) {
+	c := l.load()
+	if !c.enabled(level.Warn) {
+		return
+	}
+
+	e := Entry{
+		Level:   level.Warn,
+		Time:    c.nowFunc(),
+		Message: msg,
+	}
+	l.outputFields(c, e, fields)
+}
+
+// ErrorFields logs an error with typed fields at level Error.
+// If the level is disabled, it doesn't allocate.
+func (l *Logger) ErrorFields(err error, fields ...Field) {
+	c := l.load()
+	if !c.enabled(level.Error) || err == nil {
+		return
+	}
+
+	e := Entry{
+		Level: level.Error,
+		Time:  c.nowFunc(),
+		Error: err,
+	}
+
+	if st, ok := stackTraceOf(err); ok {
+		e.stack = st
+	}
+
+	l.outputFields(c, e, fields)
+}
+
+// outputFields outputs the entry with typed fields.
+// If no hook is added and all the sinks are formatted in JSON,
+// the entry is encoded by the JSON encoder without building the map passed to formatter.
+func (l *Logger) outputFields(c *config, e Entry, fields []Field) {
+	if c.callerMode != CallerOff {
+		e.Caller = callerOf(callerPC(c.callerSkip), c.callerMode)
+	}
+	if !c.captures(e.Level) {
+		e.Trace = nil
+	} else if e.stack != nil {
+		e.Trace = c.renderTrace(e.stack)
+	} else if e.Trace == nil {
+		e.Trace = c.captureTrace(0)
+	}
+	if len(c.hooks) == 0 && c.fastJSON && l.encodeJSON(c, &e, fields) {
+		return
+	}
+	l.outputEntry(c, e, fields)
+}
+
+func (l *Logger) outputEntry(c *config, e Entry, fields []Field) {
+	applyFields(&e, fields)
+	l.output(c, &e)
+}
+
+// encodeJSON writes the entry encoded by the JSON encoder to the sinks.
+// It returns false if the entry can't be encoded.
+func (l *Logger) encodeJSON(c *config, e *Entry, fields []Field) bool {
+	for i := range fields {
+		if fields[i].typ == errorType && e.Error == nil {
+			e.Error = fields[i].iface.(error)
 		}
 	}
 
-	err = errors.Cause(err)
-	data["error"] = fmt.Sprintf("%s: %s", reflect.TypeOf(err), err.Error())
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	b, ok := appendEntryJSON(buf.b, c, e, fields)
+	buf.b = b
+	if !ok {
+		return false
+	}
 
-	l.println(data)
+	s := string(b)
+	for _, sk := range c.sinks {
+		if sk.minLevel != "" && e.Level.LessThan(sk.minLevel) {
+			continue
+		}
+		write(c, sk, e.Level, s)
+	}
+	return true
 }
 
-func (l *Logger) println(v map[string]interface{}) {
+// output fires the hooks on the entry and prints it or forwards it to slog.Handler.
+func (l *Logger) output(c *config, e *Entry) {
+	e.Metadata = c.metadata
+	if c.callerMode != CallerOff && e.Caller == nil {
+		e.Caller = callerOf(callerPC(c.callerSkip), c.callerMode)
+	}
+	if !c.captures(e.Level) {
+		e.Trace = nil
+	} else if e.stack != nil {
+		e.Trace = c.renderTrace(e.stack)
+	} else if e.Trace == nil {
+		e.Trace = c.captureTrace(0)
+	}
+	if len(c.hooks) > 0 {
+		if e.Fields == nil {
+			e.Fields = map[string]interface{}{}
+		}
+		fireHooks(c.hooks, e)
+	}
+	if c.slogHandler != nil {
+		forward(c, e)
+		return
+	}
+	l.println(c, e)
+}
+
+func (l *Logger) println(c *config, e *Entry) {
+	lv := e.Level
+	v := e.data(&c.names)
+
 	var data map[string]interface{}
-	if l.flattenMetadata && !l.isMergeFailed {
+	if c.flattenMetadata {
 		var err error
-		data, err = merge(v, l.metadata)
+		data, err = merge(v, c, e.Fields)
 		if err != nil {
-			l.isMergeFailed = true
-			l.Error(err)
-			return
+			// the conflict is reported only once per configuration
+			// so that all the entries aren't followed by the same error.
+			if atomic.CompareAndSwapInt32(c.conflictReported, 0, 1) {
+				l.Error(err)
+			}
+			data = e.data(&c.names)
+			data[c.names.Metadata] = c.metadata
 		}
 	} else {
 		data = v
-		if l.metadata != nil {
-			v["meta"] = l.metadata
+		if c.metadata != nil {
+			v[c.names.Metadata] = c.metadata
 		}
 	}
 
-	s, err := l.formatter(data)
-	if err != nil {
-		if l.isFormatFailed {
-			return
+	// the failure of a sink is reported after the entry is written to the other sinks.
+	var formatErr error
+	for _, sk := range c.sinks {
+		if sk.minLevel != "" && lv.LessThan(sk.minLevel) {
+			continue
 		}
-		l.isFormatFailed = true
-		l.Error(err)
+		s, err := sk.format(data)
+		if err != nil {
+			if formatErr == nil {
+				formatErr = err
+			}
+			continue
+		}
+		write(c, sk, lv, s)
+	}
+	if formatErr == nil {
+		return
+	}
+
+	failed := &l.isFormatFailed
+	if l.root != nil {
+		failed = &l.root.isFormatFailed
+	}
+	if atomic.CompareAndSwapInt32(failed, 0, 1) {
+		l.Error(formatErr)
+	}
+}
+
+// write writes the formatted message to the sink.
+func write(c *config, sk sink, lv level.Level, s string) {
+	if c.async != nil {
+		c.async.enqueue(record{out: sk.out, level: lv, line: s})
 		return
 	}
-	l.logger.Println(s)
+	sk.out.Output(2, s)
 }
 
-func merge(a, b map[string]interface{}) (map[string]interface{}, error) {
-	for k, v := range b {
-		_, ok := a[k]
-		if ok {
-			return map[string]interface{}{}, errors.Errorf("the key of metadata conflicted: key=%s", k)
+// ConflictPolicy is the policy to resolve the conflicts between the keys of metadata
+// and the ones of an entry when metadata is flattened.
+type ConflictPolicy int
+
+const (
+	// ConflictError reports the conflict of metadata with the fields that the entry has,
+	// like "level" and "message", as an error once, and outputs the entry with
+	// the metadata nested in "meta", or the name set by FieldNames, as if it isn't flattened.
+	// The per-entry fields take precedence over the metadata without error.
+	// This is the default.
+	ConflictError ConflictPolicy = iota
+	// ConflictMetadataWins outputs the value of the metadata.
+	ConflictMetadataWins
+	// ConflictEntryWins outputs the value of the entry and drops the one of the metadata.
+	ConflictEntryWins
+	// ConflictRename outputs the value of the metadata with the key prefixed with the name
+	// of the metadata, "meta" by default, and the separator of FlattenNested, like "meta.level",
+	// next to the one of the entry.
+	ConflictRename
+)
+
+// merge merges the flattened metadata of c into the entry a along the conflict policy of c.
+// fields are the per-entry fields of a.
+// If the policy is ConflictError, the conflict is returned as an error, then a is left partially merged.
+func merge(a map[string]interface{}, c *config, fields map[string]interface{}) (map[string]interface{}, error) {
+	for k, v := range c.flatMetadata {
+		if _, ok := a[k]; !ok {
+			a[k] = v
+			continue
+		}
+
+		switch c.metadataConflict {
+		case ConflictMetadataWins:
+			a[k] = v
+		case ConflictEntryWins:
+		case ConflictRename:
+			a[c.conflictPrefix+k] = v
+		default:
+			if _, ok := fields[k]; ok && !isReservedKey(&c.names, k) {
+				continue
+			}
+			return a, errors.Errorf("the key of metadata conflicted: key=%s", k)
 		}
-		a[k] = v
 	}
 	return a, nil
 }
+
+// extend returns a new map that has the entries of both a and b.
+// The values of b take precedence over the values of a.
+func extend(a, b map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// invalidFieldsKey is the key of the field that has the invalid keys and values
+// passed to the XXXw functions.
+const invalidFieldsKey = "invalid_fields"
+
+// keysAndValuesToFields converts the alternating keys and values into fields.
+// The keys that aren't string and the last key that has no value
+// are output in the field of invalidFieldsKey instead of panicking.
+func keysAndValuesToFields(keysAndValues []interface{}) map[string]interface{} {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(keysAndValues)/2)
+	var invalid []interface{}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			invalid = append(invalid, keysAndValues[i])
+			break
+		}
+		k, ok := keysAndValues[i].(string)
+		if !ok {
+			invalid = append(invalid, keysAndValues[i], keysAndValues[i+1])
+			continue
+		}
+		fields[k] = keysAndValues[i+1]
+	}
+	if len(invalid) > 0 {
+		fields[invalidFieldsKey] = invalid
+	}
+	return fields
+}
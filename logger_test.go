@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,10 +20,10 @@ import (
 
 func TestLoggerSetMetadata(t *testing.T) {
 	logger := New()
-	assert.Equal(t, map[string]interface{}{}, logger.metadata)
+	assert.Equal(t, map[string]interface{}{}, logger.load().metadata)
 	meta := map[string]interface{}{"new": "meta"}
 	logger.SetMetadata(meta)
-	assert.Equal(t, meta, logger.metadata)
+	assert.Equal(t, meta, logger.load().metadata)
 }
 
 func TestNew(t *testing.T) {
@@ -30,11 +33,189 @@ func TestNew(t *testing.T) {
 	)
 
 	assert := assert.New(t)
-	assert.Equal(level.Debug, logger.level)
+	assert.Equal(level.Debug, logger.load().level)
 	f1 := runtime.FuncForPC(reflect.ValueOf(format.JSON).Pointer()).Name()
-	f2 := runtime.FuncForPC(reflect.ValueOf(logger.formatter).Pointer()).Name()
+	f2 := runtime.FuncForPC(reflect.ValueOf(logger.load().formatter).Pointer()).Name()
 	assert.Equal(f1, f2)
-	assert.Equal(map[string]interface{}{}, logger.metadata)
+	assert.Equal(map[string]interface{}{}, logger.load().metadata)
+}
+
+func TestWith(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	parent := New(
+		Format(format.JSON),
+		Output(buf),
+		Metadata(map[string]interface{}{"service": "book"}),
+	)
+	parent.update(nowFunc(func() time.Time { return time.Time{} }))
+
+	child := parent.With(map[string]interface{}{"request_id": "req1"})
+	grandchild := child.With(map[string]interface{}{"user_id": "user1", "service": "shelf"})
+
+	t.Run("the parent is not modified", func(t *testing.T) {
+		assert.Equal(map[string]interface{}{"service": "book"}, parent.load().metadata)
+	})
+
+	t.Run("nested With layers metadata on top", func(t *testing.T) {
+		assert.Equal(map[string]interface{}{"service": "book", "request_id": "req1"}, child.load().metadata)
+		assert.Equal(map[string]interface{}{
+			"service":    "shelf",
+			"request_id": "req1",
+			"user_id":    "user1",
+		}, grandchild.load().metadata)
+	})
+
+	t.Run("output messages with the merged metadata", func(t *testing.T) {
+		buf.Reset()
+		child.Info("info")
+		assert.Equal(`{"level":"INFO","message":"info","meta":{"request_id":"req1","service":"book"},"time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("the changes of the parent after With are shared", func(t *testing.T) {
+		lg := New(Format(format.JSON), Output(buf), MinLevel(level.Info))
+		lg.update(nowFunc(func() time.Time { return time.Time{} }))
+		derived := lg.With(map[string]interface{}{"request_id": "req1"}).With(nil)
+
+		buf.Reset()
+		derived.Debug("debug")
+		assert.Empty(buf.String())
+
+		lg.SetMinLevel(level.Debug)
+		out := bytes.NewBuffer(nil)
+		lg.SetOutput(out)
+		lg.SetMetadata(map[string]interface{}{"service": "book"})
+		derived.Debug("debug")
+		assert.Empty(buf.String())
+		assert.Equal(`{"level":"DEBUG","message":"debug","meta":{"request_id":"req1","service":"book"},"time":"0001-01-01T00:00:00Z"}
+`, out.String())
+
+		derived.SetMinLevel(level.Warn)
+		assert.Equal(level.Warn, lg.Level())
+		assert.Equal(map[string]interface{}{"service": "book"}, lg.load().metadata)
+	})
+
+	t.Run("the setters of the metadata change only the derived logger", func(t *testing.T) {
+		lg := New(Format(format.JSON), Output(buf), Metadata(map[string]interface{}{"service": "book"}))
+		lg.update(nowFunc(func() time.Time { return time.Time{} }))
+		derived := lg.With(map[string]interface{}{"request_id": "req1"})
+
+		derived.SetMetadata(map[string]interface{}{"user": "user1"})
+		derived.SetFlattenMetadata(true)
+		derived.SetFieldNames(format.Schema{Message: "msg"})
+		grandchild := derived.With(map[string]interface{}{"request_id": "req2"})
+
+		buf.Reset()
+		lg.Info("info")
+		derived.Info("info")
+		grandchild.Info("info")
+		assert.Equal(`{"level":"INFO","message":"info","meta":{"service":"book"},"time":"0001-01-01T00:00:00Z"}
+{"level":"INFO","msg":"info","service":"book","time":"0001-01-01T00:00:00Z","user":"user1"}
+{"level":"INFO","msg":"info","request_id":"req2","service":"book","time":"0001-01-01T00:00:00Z","user":"user1"}
+`, buf.String())
+
+		lg.SetMetadata(map[string]interface{}{"service": "shelf"})
+		buf.Reset()
+		derived.Info("info")
+		assert.Equal(`{"level":"INFO","msg":"info","service":"shelf","time":"0001-01-01T00:00:00Z","user":"user1"}
+`, buf.String())
+	})
+
+	t.Run("flattened metadata is conflict-checked", func(t *testing.T) {
+		buf.Reset()
+		lg := New(Format(format.JSON), Output(buf), FlattenMetadata(true))
+		lg.update(nowFunc(func() time.Time { return time.Time{} }))
+		lg.update(withoutTrace(true))
+
+		lg.With(map[string]interface{}{"request_id": "req1"}).Info("info")
+		assert.Equal(`{"level":"INFO","message":"info","request_id":"req1","time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+
+		buf.Reset()
+		lg.With(map[string]interface{}{"message": "conflict"}).Info("info")
+		var mp map[string]interface{}
+		assert.NoError(json.NewDecoder(buf).Decode(&mp))
+		assert.Equal(errorJSON("*errors.fundamental", "the key of metadata conflicted: key=message"), mp["error"])
+	})
+}
+
+func TestTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		Format(format.JSON),
+		Output(buf),
+	)
+	logger.update(nowFunc(func() time.Time { return time.Time{} }))
+
+	t.Run("if minimum level is Debug, output nothing", func(t *testing.T) {
+		logger.Trace("trace")
+		logger.Tracef("formatted: %s", "trace")
+		assert.Empty(buf.String())
+	})
+
+	logger.SetMinLevel(level.Trace)
+
+	t.Run("if minimum level is Trace, output messages correctly", func(t *testing.T) {
+		logger.Trace("trace")
+		logger.Tracef("formatted: %s", "trace")
+		assert.Equal(`{"level":"TRACE","message":"trace","meta":{},"time":"0001-01-01T00:00:00Z"}
+{"level":"TRACE","message":"formatted: trace","meta":{},"time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+}
+
+// the custom levels are registered once because they can't be unregistered.
+var (
+	testNotice = level.MustRegister("TEST_NOTICE", 35)
+	testAlert  = level.MustRegister("TEST_ALERT", 55)
+)
+
+func TestLog(t *testing.T) {
+	assert := assert.New(t)
+
+	notice, alert := testNotice, testAlert
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		MinLevel(notice),
+		Format(format.JSON),
+		Output(buf),
+	)
+	logger.update(nowFunc(func() time.Time { return time.Time{} }))
+
+	t.Run("output messages at custom levels", func(t *testing.T) {
+		buf.Reset()
+		logger.Info("info")
+		logger.Log(notice, "notice")
+		logger.Logf(notice, "formatted: %s", "notice")
+		assert.Equal(`{"level":"TEST_NOTICE","message":"notice","meta":{},"time":"0001-01-01T00:00:00Z"}
+{"level":"TEST_NOTICE","message":"formatted: notice","meta":{},"time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("add trace if level is higher than Warn", func(t *testing.T) {
+		buf.Reset()
+		logger.Log(alert, "alert")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("TEST_ALERT", mp["level"])
+		assert.NotEmpty(mp["trace"])
+	})
+
+	t.Run("if level isn't registered, output nothing", func(t *testing.T) {
+		buf.Reset()
+		logger.Log(level.Level("UNREGISTERED"), "unregistered")
+		assert.Empty(buf.String())
+	})
+
+	t.Run("if minimum level isn't registered, it isn't changed", func(t *testing.T) {
+		logger.SetMinLevel(level.Level("UNREGISTERED"))
+		assert.Equal(notice, logger.load().level)
+	})
 }
 
 func TestDebug(t *testing.T) {
@@ -47,7 +228,7 @@ func TestDebug(t *testing.T) {
 			Format(format.JSONPretty),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 		t.Run("output messages correctly", func(t *testing.T) {
 			logger.Debug("debug")
@@ -76,7 +257,7 @@ func TestDebug(t *testing.T) {
 			Format(format.JSON),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 		logger.Debug("debug")
 		assert.Empty(buf.String())
 	})
@@ -92,7 +273,7 @@ func TestDebugf(t *testing.T) {
 			Format(format.JSONPretty),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 		t.Run("output messages correctly", func(t *testing.T) {
 			logger.Debugf("formatted: %s", "debug")
@@ -116,7 +297,7 @@ func TestDebugf(t *testing.T) {
 			Format(format.JSON),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 		logger.Debugf("formatted: %s", "debug")
 		assert.Empty(buf.String())
 	})
@@ -132,7 +313,7 @@ func TestInfo(t *testing.T) {
 			Format(format.JSONPretty),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 		t.Run("output messages correctly", func(t *testing.T) {
 			logger.Info("info")
@@ -161,7 +342,7 @@ func TestInfo(t *testing.T) {
 			Format(format.JSON),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 		logger.Info("info")
 		assert.Empty(buf.String())
 	})
@@ -177,7 +358,7 @@ func TestInfof(t *testing.T) {
 			Format(format.JSONPretty),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 		t.Run("output messages correctly", func(t *testing.T) {
 			logger.Infof("formatted: %s", "info")
@@ -200,7 +381,7 @@ func TestInfof(t *testing.T) {
 			Format(format.JSON),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 		logger.Infof("formatted: %s", "info")
 		assert.Empty(buf.String())
 	})
@@ -216,7 +397,7 @@ func TestWarn(t *testing.T) {
 			Format(format.JSONPretty),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 		t.Run("output messages correctly", func(t *testing.T) {
 			logger.Warn(errors.New("warn"))
@@ -244,7 +425,7 @@ func TestWarn(t *testing.T) {
 			Format(format.JSON),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 		logger.Warn("warn")
 		assert.Empty(buf.String())
 	})
@@ -260,7 +441,7 @@ func TestWarnf(t *testing.T) {
 			Format(format.JSONPretty),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 		t.Run("output messages correctly", func(t *testing.T) {
 			logger.Warnf("formatted: %s: %s", errors.New("error"), "warn")
@@ -282,7 +463,7 @@ func TestWarnf(t *testing.T) {
 			Format(format.JSON),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 		logger.Warnf("warn")
 		assert.Empty(buf.String())
 	})
@@ -290,6 +471,11 @@ func TestWarnf(t *testing.T) {
 
 type MyError error
 
+// errorJSON returns the error field of an error that wraps nothing decoded from JSON.
+func errorJSON(typ, message string) []interface{} {
+	return []interface{}{map[string]interface{}{"type": typ, "message": message}}
+}
+
 func TestError(t *testing.T) {
 	assert := assert.New(t)
 
@@ -300,14 +486,14 @@ func TestError(t *testing.T) {
 			Format(format.JSONPretty),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 		t.Run("output messages correctly", func(t *testing.T) {
 			logger.Error(MyError(fmt.Errorf("error")))
 			var mp map[string]interface{}
 			assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
 			assert.Equal("ERROR", mp["level"])
-			assert.Equal("*errors.errorString: error", mp["error"])
+			assert.Equal(errorJSON("*errors.errorString", "error"), mp["error"])
 			assert.Equal("0001-01-01T00:00:00Z", mp["time"])
 			assert.NotEmpty(mp["trace"])
 		})
@@ -324,16 +510,85 @@ func TestError(t *testing.T) {
 	t.Run("if minimum level is lower than Error, output nothing", func(t *testing.T) {
 		buf := bytes.NewBuffer(nil)
 		logger := New(
-			MinLevel(level.Level("EXTREAM")),
+			MinLevel(level.Panic),
 			Format(format.JSON),
 			Output(buf),
 		)
-		logger.nowFunc = func() time.Time { return time.Time{} }
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
 		logger.Error(MyError(fmt.Errorf("error")))
 		assert.Empty(buf.String())
 	})
 }
 
+func TestW(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		Format(format.JSON),
+		Output(buf),
+		Metadata(map[string]interface{}{"service": "book"}),
+		nowFunc(func() time.Time { return time.Time{} }),
+		withoutTrace(true),
+	)
+
+	t.Run("output fields as first-class fields", func(t *testing.T) {
+		buf.Reset()
+		logger.Infow("info", "request_id", "req1", "count", 1)
+		assert.Equal(`{"count":1,"level":"INFO","message":"info","meta":{"service":"book"},"request_id":"req1","time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("invalid keys and values are output as a special field", func(t *testing.T) {
+		buf.Reset()
+		logger.Debugw("debug", 1, "one", "request_id", "req1", "dangling")
+		assert.Equal(`{"invalid_fields":[1,"one","dangling"],"level":"DEBUG","message":"debug","meta":{"service":"book"},"request_id":"req1","time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("reserved keys are not overwritten", func(t *testing.T) {
+		buf.Reset()
+		logger.Warnw("warn", "level", "INFO")
+		assert.Equal(`{"level":"WARN","message":"warn","meta":{"service":"book"},"time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("Errorw outputs the error with fields", func(t *testing.T) {
+		buf.Reset()
+		logger.Errorw(errors.New("error"), "request_id", "req1")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal(errorJSON("*errors.fundamental", "error"), mp["error"])
+		assert.Equal("req1", mp["request_id"])
+	})
+
+	t.Run("fields take precedence over flattened metadata", func(t *testing.T) {
+		buf.Reset()
+		logger.SetFlattenMetadata(true)
+		defer logger.SetFlattenMetadata(false)
+		logger.Infow("info", "service", "shelf", "request_id", "req1")
+		assert.Equal(`{"level":"INFO","message":"info","request_id":"req1","service":"shelf","time":"0001-01-01T00:00:00Z"}
+`, buf.String())
+	})
+
+	t.Run("if minimum level is higher, output nothing", func(t *testing.T) {
+		buf.Reset()
+		logger.SetMinLevel(level.Error)
+		defer logger.SetMinLevel(level.Debug)
+		logger.Infow("info", "request_id", "req1")
+		assert.Empty(buf.String())
+	})
+
+	t.Run("the console format outputs the error chain as the causes", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		logger := New(Format(format.ConsoleColor(false)), Output(buf), withoutTrace(true))
+		logger.update(nowFunc(func() time.Time { return time.Time{} }))
+
+		logger.Error(fmt.Errorf("wrap: %w", errors.New("root")))
+		assert.Equal("ERROR 00:00:00.000 wrap: root\n    caused by: root\n", buf.String())
+	})
+}
+
 func TestErrorMetadataConflicted(t *testing.T) {
 	assert := assert.New(t)
 	buf := bytes.NewBuffer(nil)
@@ -342,19 +597,347 @@ func TestErrorMetadataConflicted(t *testing.T) {
 	}
 	logger := New(
 		Output(buf),
+		Format(format.JSON),
 		Metadata(meta),
 		FlattenMetadata(true),
 	)
 
-	logger.nowFunc = func() time.Time { return time.Time{} }
+	logger.update(nowFunc(func() time.Time { return time.Time{} }))
 
 	logger.Error(fmt.Errorf("error"))
 
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(lines, 2)
+
+	var report map[string]interface{}
+	assert.NoError(json.Unmarshal([]byte(lines[0]), &report))
+	assert.Equal("ERROR", report["level"])
+	assert.Equal(errorJSON("*errors.fundamental", "the key of metadata conflicted: key=error"), report["error"])
+	assert.Equal(meta, report["meta"])
+	assert.Equal("0001-01-01T00:00:00Z", report["time"])
+	assert.NotEmpty(report["trace"])
+
 	var mp map[string]interface{}
-	assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+	assert.NoError(json.Unmarshal([]byte(lines[1]), &mp))
 	assert.Equal("ERROR", mp["level"])
-	assert.Equal("*errors.fundamental: the key of metadata conflicted: key=error", mp["error"])
+	assert.Equal(errorJSON("*errors.errorString", "error"), mp["error"])
 	assert.Equal(meta, mp["meta"])
-	assert.Equal("0001-01-01T00:00:00Z", mp["time"])
+
+	t.Run("the conflict is reported once", func(t *testing.T) {
+		buf.Reset()
+		logger.Error(fmt.Errorf("error"))
+		assert.Equal(1, strings.Count(buf.String(), "\n"))
+	})
+}
+
+func TestMetadataConflict(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		Output(buf),
+		Format(format.JSON),
+		Metadata(map[string]interface{}{
+			"level": "meta-level",
+			"key":   "meta",
+			"trace": "meta-trace",
+		}),
+		FlattenMetadata(true),
+	)
+	logger.update(nowFunc(func() time.Time { return time.Time{} }))
+
+	for _, tc := range []struct {
+		policy ConflictPolicy
+		want   map[string]interface{}
+	}{
+		{ConflictMetadataWins, map[string]interface{}{
+			"level": "meta-level", "message": "info", "key": "meta", "trace": "meta-trace", "time": "0001-01-01T00:00:00Z",
+		}},
+		{ConflictEntryWins, map[string]interface{}{
+			"level": "INFO", "message": "info", "key": "entry", "trace": "meta-trace", "time": "0001-01-01T00:00:00Z",
+		}},
+		{ConflictRename, map[string]interface{}{
+			"level": "INFO", "message": "info", "key": "entry", "trace": "meta-trace", "time": "0001-01-01T00:00:00Z",
+			"meta.level": "meta-level", "meta.key": "meta",
+		}},
+	} {
+		logger.SetMetadataConflict(tc.policy)
+
+		buf.Reset()
+		logger.Infow("info", "key", "entry")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal(tc.want, mp, "policy %d", tc.policy)
+
+		buf.Reset()
+		logger.InfoFields("info", String("key", "entry"))
+		mp = nil
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal(tc.want, mp, "policy %d with typed fields", tc.policy)
+	}
+
+	t.Run("flattening isn't turned off by a conflict", func(t *testing.T) {
+		logger.SetMetadata(map[string]interface{}{"trace": "meta-trace"})
+		logger.SetMetadataConflict(ConflictError)
+
+		buf.Reset()
+		logger.Warn("warn")
+		assert.Contains(buf.String(), "the key of metadata conflicted: key=trace")
+
+		buf.Reset()
+		logger.Info("info")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("meta-trace", mp["trace"])
+		assert.Nil(mp["meta"])
+	})
+}
+
+func TestFieldNamesOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	lg := New(
+		FormatLayout(JSONLayout),
+		Output(buf),
+		Metadata(map[string]interface{}{"service": "book"}),
+		FieldNames(format.Schema{
+			Level:    "severity",
+			Time:     "@timestamp",
+			Message:  "msg",
+			Error:    "err",
+			Trace:    "stack_trace",
+			Metadata: "labels",
+		}),
+		StackTraces(TraceConfig{Levels: []level.Level{level.Error}}),
+	)
+	lg.update(nowFunc(func() time.Time { return time.Time{} }))
+
+	t.Run("the JSON encoder and formatter output the same names", func(t *testing.T) {
+		buf.Reset()
+		lg.InfoFields("info", String("level", "field"), Err(errors.New("error")))
+		s1 := buf.String()
+
+		buf.Reset()
+		lg.Infow("info", "level", "field", "err", "ignored")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal(map[string]interface{}{
+			"severity":   "INFO",
+			"@timestamp": "0001-01-01T00:00:00Z",
+			"msg":        "info",
+			"level":      "field",
+			"labels":     map[string]interface{}{"service": "book"},
+		}, mp)
+
+		mp = nil
+		assert.NoError(json.Unmarshal([]byte(s1), &mp))
+		assert.Equal("field", mp["level"])
+		assert.Equal(errorJSON("*errors.fundamental", "error"), mp["err"])
+		assert.NotContains(mp, "error")
+	})
+
+	t.Run("the trace is renamed", func(t *testing.T) {
+		buf.Reset()
+		lg.Error(errors.New("error"))
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.NotEmpty(mp["stack_trace"])
+		assert.Equal(errorJSON("*errors.fundamental", "error"), mp["err"])
+	})
+
+	t.Run("the conflicts are checked with the names", func(t *testing.T) {
+		buf.Reset()
+		flat := lg.With(map[string]interface{}{"level": "meta", "msg": "meta"})
+		flat.update(FlattenMetadata(true), MetadataConflict(ConflictRename))
+		flat.Info("info")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("meta", mp["level"])
+		assert.Equal("info", mp["msg"])
+		assert.Equal("meta", mp["labels.msg"])
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	logger := New(Output(ioutil.Discard), Format(format.JSON), withoutTrace(true))
+	meta := map[string]interface{}{"key": "value"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.SetMinLevel(level.Debug)
+				logger.SetFormat(format.JSONPretty)
+				logger.SetMetadata(meta)
+				logger.SetFlattenMetadata(j%2 == 0)
+				logger.SetOutput(ioutil.Discard)
+				SetMinLevel(level.Debug)
+				SetOutput(ioutil.Discard)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Debug("debug")
+				logger.Infof("info %d", j)
+				logger.Warn("warn")
+				logger.Error(errors.New("error"))
+				logger.With(map[string]interface{}{"j": j}).Info("info")
+				Info("info")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestErrorVariants(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		Format(format.JSON),
+		Output(buf),
+		nowFunc(func() time.Time { return time.Time{} }),
+	)
+
+	decode := func() map[string]interface{} {
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		buf.Reset()
+		return mp
+	}
+
+	t.Run("Errorf builds an error with the stack trace", func(t *testing.T) {
+		logger.Errorf("formatted: %s", "error")
+		mp := decode()
+		assert.Equal("ERROR", mp["level"])
+		assert.Equal(errorJSON("*errors.errorString", "formatted: error"), mp["error"])
+		trace := mp["trace"].([]interface{})
+		assert.Contains(trace[0], "TestErrorVariants")
+	})
+
+	t.Run("Errorf captures the stack trace along the configuration", func(t *testing.T) {
+		lg := New(
+			Format(format.JSON),
+			Output(buf),
+			CallerSkip(1),
+			StackTraces(TraceConfig{MaxDepth: 64}),
+		)
+		var wrapLine int
+		wrapErrorf := func() { _, _, wrapLine, _ = runtime.Caller(0); lg.Errorf("error") }
+		var deep func(n int)
+		deep = func(n int) {
+			if n == 0 {
+				wrapErrorf()
+				return
+			}
+			deep(n - 1)
+		}
+		deep(40)
+
+		mp := decode()
+		trace := mp["trace"].([]interface{})
+		assert.Contains(trace[0], "TestErrorVariants.func")
+		assert.False(strings.HasSuffix(trace[0].(string), fmt.Sprintf(":%d", wrapLine)), "the wrapper is skipped")
+		assert.Greater(len(trace), 32)
+	})
+
+	t.Run("Errorf wraps the errors of %w", func(t *testing.T) {
+		logger.Errorf("wrapped: %w", fmt.Errorf("error"))
+		mp := decode()
+		assert.Equal([]interface{}{
+			map[string]interface{}{"type": "*fmt.wrapError", "message": "wrapped: error"},
+			map[string]interface{}{"type": "*errors.errorString", "message": "error"},
+		}, mp["error"])
+	})
+
+	t.Run("ErrorMsg outputs the message and the error", func(t *testing.T) {
+		logger.ErrorMsg(fmt.Errorf("error"), "failed to do")
+		mp := decode()
+		assert.Equal("failed to do", mp["message"])
+		assert.Equal(errorJSON("*errors.errorString", "error"), mp["error"])
+		assert.NotEmpty(mp["trace"])
+	})
+
+	t.Run("ErrorWith outputs the error with fields", func(t *testing.T) {
+		fields := map[string]interface{}{"request_id": "req1"}
+		logger.ErrorWith(fmt.Errorf("error"), fields)
+		mp := decode()
+		assert.Equal("req1", mp["request_id"])
+		assert.Equal(errorJSON("*errors.errorString", "error"), mp["error"])
+		assert.Nil(mp["message"])
+	})
+
+	t.Run("if nil is passed, output nothing", func(t *testing.T) {
+		logger.ErrorMsg(nil, "msg")
+		logger.ErrorWith(nil, nil)
+		assert.Empty(buf.String())
+	})
+}
+
+func TestFatal(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	var calls []string
+	logger := New(
+		Format(format.JSON),
+		Output(buf),
+		Async(16, Block),
+		ExitHooks(func() { calls = append(calls, "hook1") }),
+		ExitFunc(func(code int) { calls = append(calls, fmt.Sprintf("exit %d", code)) }),
+		nowFunc(func() time.Time { return time.Time{} }),
+	)
+	logger.AddExitHooks(func() {
+		calls = append(calls, "hook2")
+		// the message has been flushed before the exit hooks are called.
+		assert.NotEmpty(buf.String())
+	})
+
+	t.Run("Fatal logs, flushes, calls exit hooks and exits", func(t *testing.T) {
+		logger.Fatal("fatal")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("FATAL", mp["level"])
+		assert.Equal("fatal", mp["message"])
+		assert.NotEmpty(mp["trace"])
+		assert.Equal([]string{"hook1", "hook2", "exit 1"}, calls)
+	})
+
+	buf.Reset()
+	calls = nil
+
+	t.Run("Fatalf logs a formatted message", func(t *testing.T) {
+		logger.Fatalf("formatted: %s", "fatal")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("formatted: fatal", mp["message"])
+		assert.Equal([]string{"hook1", "hook2", "exit 1"}, calls)
+	})
+}
+
+func TestPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		Format(format.JSON),
+		Output(buf),
+		nowFunc(func() time.Time { return time.Time{} }),
+	)
+
+	assert.PanicsWithValue("panic", func() { logger.Panic("panic") })
+	var mp map[string]interface{}
+	assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+	assert.Equal("PANIC", mp["level"])
+	assert.Equal("panic", mp["message"])
 	assert.NotEmpty(mp["trace"])
+
+	buf.Reset()
+	assert.PanicsWithValue("formatted: panic", func() { logger.Panicf("formatted: %s", "panic") })
+	assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+	assert.Equal("formatted: panic", mp["message"])
 }
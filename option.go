@@ -1,28 +1,128 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"log"
+	"os"
 
+	"github.com/kyfk/log/format"
 	"github.com/kyfk/log/level"
 )
 
 // Option is a function for initialization in the constructor of Logger.
-type Option func(Logger) Logger
+type Option func(config) config
 
 // MinLevel returns Option that sets minumum logging level to a new logger.
+// If lv isn't registered, the minimum logging level isn't changed and it is written to stderr.
 func MinLevel(lv level.Level) Option {
-	return func(l Logger) Logger {
-		l.level = lv
-		return l
+	return func(c config) config {
+		if !lv.Registered() {
+			reportUnregistered(lv)
+			return c
+		}
+		c.level = lv
+		return c
+	}
+}
+
+// LevelFromEnv returns Option that sets the minimum logging level parsed from
+// the environment variable key, such as LOG_LEVEL, to a new logger.
+// The value is parsed by level.Parse. If the variable is unset, the minimum logging level
+// isn't changed, and if it is invalid, the error is written to stderr as well.
+func LevelFromEnv(key string) Option {
+	return func(c config) config {
+		s, ok := os.LookupEnv(key)
+		if !ok {
+			return c
+		}
+		lv, err := level.Parse(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "log: invalid level in %s: %v\n", key, err)
+			return c
+		}
+		c.level = lv
+		return c
+	}
+}
+
+// LevelOverrides returns Option that sets the minimum logging levels for the callers
+// in specific packages to a new logger, which override the minimum logging level.
+// spec is the comma-separated list of pattern=level like "billing/*=debug,github.com/foo/*=warn".
+//
+// The pattern is matched with path.Match against the import path of the caller's package
+// and its suffixes after "/", so "billing" matches "github.com/acme/internal/billing".
+// The pattern ending with "/..." matches the package and all the packages under it,
+// and the one ending with "/*" matches the package and the packages right under it.
+// If several patterns match, the first one is used.
+// The level for each call site is looked up once and cached.
+//
+// If spec is empty, the overrides are removed, and if it is invalid,
+// the overrides aren't changed and the error is written to stderr.
+func LevelOverrides(spec string) Option {
+	o, err := parseLevelOverrides(spec)
+	return func(c config) config {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return c
+		}
+		c.overrides = o
+		return c
+	}
+}
+
+// Caller returns Option that sets the mode of the caller field to a new logger.
+// The caller field is the function, file and line where the logger is called.
+func Caller(mode CallerMode) Option {
+	return func(c config) config {
+		c.callerMode = mode
+		return c
+	}
+}
+
+// CallerSkip returns Option that sets the number of frames skipped to find the caller to a new logger.
+// The frames of this package, including the package-level functions, are always skipped,
+// so it is only needed by the libraries that wrap Logger.
+// For instance, a function that calls Logger directly sets 1 so that its caller is reported.
+// It is also used to find the caller for LevelOverrides.
+func CallerSkip(n int) Option {
+	return func(c config) config {
+		if n < 0 {
+			n = 0
+		}
+		c.callerSkip = n
+		return c
+	}
+}
+
+// StackTraces returns Option that sets the configuration of the stack traces to a new logger.
+func StackTraces(tc TraceConfig) Option {
+	if tc.Levels != nil {
+		tc.Levels = append([]level.Level{}, tc.Levels...)
+	}
+	return func(c config) config {
+		c.trace = tc
+		return c
 	}
 }
 
 // Format returns Option that sets the format of message output to a new logger.
-func Format(fm formatter) Option {
-	return func(l Logger) Logger {
-		l.formatter = fm
-		return l
+// fm is used as it is, so the formats of the format package lay out the default names of the fields.
+func Format(fm Formatter) Option {
+	return func(c config) config {
+		c.formatter = fm
+		c.layout = nil
+		return c
+	}
+}
+
+// FormatLayout returns Option that sets the layout that builds the format of message output
+// to a new logger, like JSONLayout, LogfmtLayout and ConsoleLayout.
+// It is used instead of the format set by Format.
+func FormatLayout(ly Layout) Option {
+	return func(c config) config {
+		c.layout = ly
+		return c
 	}
 }
 
@@ -32,33 +132,154 @@ func Format(fm formatter) Option {
 // set additional information to be able to search for conveniently.
 // For instance, HTTP Request ID, the id of user signed in, EC2 instance-id and other more.
 func Metadata(md map[string]interface{}) Option {
-	return func(l Logger) Logger {
-		l.metadata = md
-		return l
+	return func(c config) config {
+		c.metadata = copyMetadata(md)
+		return c
 	}
 }
 
 // Output returns Option that sets io.Writer as the destination of logging message to a new logger.
 func Output(out io.Writer) Option {
-	return func(l Logger) Logger {
-		l.logger = log.New(out, "", 0)
-		return l
+	return func(c config) config {
+		c.sinks = []sink{{out: log.New(out, "", 0)}}
+		return c
+	}
+}
+
+// Outputs returns Option that sets sinks as the destinations of logging message to a new logger.
+// Each logging message is written to all the sinks along their minimum level and format.
+// If the minimum level of a sink isn't registered, it is written to stderr
+// and all messages that the logger outputs are written to the sink.
+func Outputs(sinks ...Sink) Option {
+	return func(c config) config {
+		c.sinks = make([]sink, len(sinks))
+		for i, s := range sinks {
+			minLevel := s.MinLevel
+			if minLevel != "" && !minLevel.Registered() {
+				reportUnregistered(minLevel)
+				minLevel = ""
+			}
+			c.sinks[i] = sink{
+				out:       log.New(s.Writer, "", 0),
+				minLevel:  minLevel,
+				formatter: s.Formatter,
+				layout:    s.Layout,
+			}
+		}
+		return c
 	}
 }
 
+// reportUnregistered writes the unregistered level lv to stderr,
+// which is ignored as a minimum logging level.
+func reportUnregistered(lv level.Level) {
+	fmt.Fprintf(os.Stderr, "log: unregistered level %q is ignored\n", lv)
+}
+
 // StdLogger returns Option that sets StdLogger that is used output message to a new logger.
 func StdLogger(lg *log.Logger) Option {
-	return func(l Logger) Logger {
-		l.logger = lg
-		return l
+	return func(c config) config {
+		c.sinks = []sink{{out: lg}}
+		return c
+	}
+}
+
+// FieldNames returns Option that sets the names of the keys of the fields
+// that a new logger outputs for entries, like format.GCPSchema.
+// The empty names of s are the default ones.
+// If s has the same name for two fields, it is reported to stderr and the names aren't changed.
+// The layouts like LogfmtLayout and ConsoleLayout build the formats with the names,
+// while format.Logfmt and format.Console set by Format lay out the default names.
+func FieldNames(s format.Schema) Option {
+	err := s.Validate()
+	return func(c config) config {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return c
+		}
+		c.names = s
+		return c
 	}
 }
 
 // FlattenMetadata returns Option that sets the flag if metadata is going to be flattened.
 // If the flag is put on, metadata is going to be flattened in output.
 func FlattenMetadata(b bool) Option {
-	return func(l Logger) Logger {
-		l.flattenMetadata = b
-		return l
+	return func(c config) config {
+		c.flattenMetadata = b
+		return c
+	}
+}
+
+// FlattenNested returns Option that sets the flattening of the maps and the structs
+// nested in metadata into the keys joined with sep, like "http.request.method",
+// when metadata is flattened by FlattenMetadata.
+// The keys have at most maxDepth parts, and the values nested deeper are output as they are.
+// If maxDepth is 0 or less, there is no limit. If sep is empty, nested metadata isn't flattened.
+// The structs are flattened along their JSON encoding unless they encode themselves.
+// format.Unflatten reverses it.
+func FlattenNested(sep string, maxDepth int) Option {
+	return func(c config) config {
+		c.flattenSep = sep
+		c.flattenDepth = maxDepth
+		return c
+	}
+}
+
+// MetadataConflict returns Option that sets the policy to resolve the conflicts
+// between the keys of flattened metadata and the ones of entries to a new logger.
+func MetadataConflict(p ConflictPolicy) Option {
+	return func(c config) config {
+		c.metadataConflict = p
+		return c
+	}
+}
+
+// Hooks returns Option that adds hooks to a new logger.
+// The hooks are fired in order of addition before an entry is formatted.
+func Hooks(hs ...Hook) Option {
+	return func(c config) config {
+		c.hooks = append(append([]Hook(nil), c.hooks...), hs...)
+		return c
+	}
+}
+
+// Async returns Option that makes a new logger asynchronous.
+// Messages are formatted in the logging call and buffered up to size,
+// then written by a background goroutine.
+// When the buffer is full, the message is handled along policy.
+// Call Flush or Close of the logger to write the buffered messages before the program exits.
+// Panic and Fatal flush them by themselves.
+func Async(size int, policy OverflowPolicy) Option {
+	return func(c config) config {
+		c.async = newAsyncWriter(size, policy)
+		return c
+	}
+}
+
+// ExitHooks returns Option that adds functions called before a new logger exits the program in Fatal.
+// The functions are called in order of addition after the buffered messages are flushed.
+func ExitHooks(fs ...func()) Option {
+	return func(c config) config {
+		c.exitHooks = append(append(([]func())(nil), c.exitHooks...), fs...)
+		return c
+	}
+}
+
+// ExitFunc returns Option that sets the function a new logger calls to exit the program in Fatal.
+// The default is os.Exit. It is mainly used for testing the paths of Fatal.
+func ExitFunc(f func(int)) Option {
+	return func(c config) config {
+		c.exitFunc = f
+		return c
+	}
+}
+
+// copyMetadata returns a copy of md so that later modifications of md
+// by the caller don't race with logging.
+func copyMetadata(md map[string]interface{}) map[string]interface{} {
+	if md == nil {
+		return nil
 	}
+	return extend(md, nil)
 }
@@ -1,11 +1,13 @@
 package log
 
 import (
+	"io"
 	"log"
 	"os"
 	"reflect"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/kyfk/log/format"
 	"github.com/kyfk/log/level"
@@ -14,20 +16,62 @@ import (
 
 func TestMinLevel(t *testing.T) {
 	assert := assert.New(t)
-	lg1 := MinLevel(level.Debug)(Logger{})
+	lg1 := MinLevel(level.Debug)(config{})
 	assert.Equal(level.Debug, lg1.level)
-	lg2 := MinLevel(level.Error)(Logger{})
+	lg2 := MinLevel(level.Error)(config{})
 	assert.Equal(level.Error, lg2.level)
+
+	var lg3 config
+	stderr := captureStderr(t, func() {
+		lg3 = MinLevel(level.Level("WARNING"))(config{level: level.Info})
+	})
+	assert.Equal(level.Info, lg3.level)
+	assert.Equal("log: unregistered level \"WARNING\" is ignored\n", stderr)
+}
+
+// captureStderr returns what f writes to stderr.
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	f()
+	w.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLevelFromEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("TEST_LOG_LEVEL", "warning")
+	lg1 := LevelFromEnv("TEST_LOG_LEVEL")(config{level: level.Debug})
+	assert.Equal(level.Warn, lg1.level)
+
+	t.Setenv("TEST_LOG_LEVEL", "extream")
+	lg2 := LevelFromEnv("TEST_LOG_LEVEL")(config{level: level.Debug})
+	assert.Equal(level.Debug, lg2.level)
+
+	lg3 := LevelFromEnv("TEST_LOG_LEVEL_UNSET")(config{level: level.Debug})
+	assert.Equal(level.Debug, lg3.level)
 }
 
 func TestFormat(t *testing.T) {
 	assert := assert.New(t)
-	lg1 := Format(format.JSON)(Logger{})
+	lg1 := Format(format.JSON)(config{})
 	f1 := runtime.FuncForPC(reflect.ValueOf(format.JSON).Pointer()).Name()
 	f2 := runtime.FuncForPC(reflect.ValueOf(lg1.formatter).Pointer()).Name()
 	assert.Equal(f1, f2)
 
-	lg2 := Format(format.JSONPretty)(Logger{})
+	lg2 := Format(format.JSONPretty)(config{})
 	f3 := runtime.FuncForPC(reflect.ValueOf(format.JSONPretty).Pointer()).Name()
 	f4 := runtime.FuncForPC(reflect.ValueOf(lg2.formatter).Pointer()).Name()
 	assert.Equal(f3, f4)
@@ -37,32 +81,67 @@ func TestMetadata(t *testing.T) {
 	assert := assert.New(t)
 
 	meta1 := map[string]interface{}{}
-	lg1 := Metadata(meta1)(Logger{})
+	lg1 := Metadata(meta1)(config{})
 	assert.Equal(meta1, lg1.metadata)
 
 	meta2 := map[string]interface{}{}
-	lg2 := Metadata(meta2)(Logger{})
+	lg2 := Metadata(meta2)(config{})
 	assert.Equal(meta2, lg2.metadata)
 }
 
 func TestFlattenMetadata(t *testing.T) {
 	assert := assert.New(t)
 
-	lg1 := FlattenMetadata(true)(Logger{})
+	lg1 := FlattenMetadata(true)(config{})
 	assert.Equal(true, lg1.flattenMetadata)
 
-	lg2 := FlattenMetadata(false)(Logger{})
+	lg2 := FlattenMetadata(false)(config{})
 	assert.Equal(false, lg2.flattenMetadata)
 }
 
+func TestFieldNames(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := FieldNames(format.Schema{Level: "severity"})(config{})
+	c1.prepare()
+	assert.Equal("severity", c1.names.Level)
+	assert.Equal("message", c1.names.Message)
+
+	c2 := FieldNames(format.Schema{Level: "msg", Message: "msg"})(config{names: format.GCPSchema})
+	assert.Equal(format.GCPSchema, c2.names)
+}
+
 func TestStdLogger(t *testing.T) {
 	assert := assert.New(t)
 
 	slg1 := log.New(os.Stdout, "", 0)
-	lg1 := StdLogger(slg1)(Logger{})
-	assert.Equal(slg1, lg1.logger)
+	lg1 := StdLogger(slg1)(config{})
+	assert.Equal(slg1, lg1.sinks[0].out)
 
 	slg2 := log.New(os.Stdout, "", 0)
-	lg2 := StdLogger(slg2)(Logger{})
-	assert.Equal(slg2, lg2.logger)
+	lg2 := StdLogger(slg2)(config{})
+	assert.Equal(slg2, lg2.sinks[0].out)
+}
+
+func TestMetadataIsCopied(t *testing.T) {
+	meta := map[string]interface{}{"key": "value"}
+	lg := Metadata(meta)(config{})
+	meta["key"] = "modified"
+	assert.Equal(t, map[string]interface{}{"key": "value"}, lg.metadata)
+}
+
+// nowFunc returns Option that replaces the clock of a new logger for testing.
+func nowFunc(f func() time.Time) Option {
+	return func(c config) config {
+		c.nowFunc = f
+		return c
+	}
+}
+
+// withoutTrace returns Option that disables stack traces of a new logger for testing.
+func withoutTrace(b bool) Option {
+	return func(c config) config {
+		c.withoutTrace = b
+		return c
+	}
 }
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/kyfk/log/level"
+)
+
+// levelOverride is a rule of the spec of LevelOverrides.
+type levelOverride struct {
+	pattern string
+	level   level.Level
+}
+
+// levelOverrides is the minimum logging levels that override the level of the logger
+// for the callers in the packages matching the patterns.
+type levelOverrides struct {
+	rules    []levelOverride
+	min, max level.Level // the lowest and highest levels of the rules
+
+	// cache caches the level per program counter of caller.
+	// The empty level means that no rule matches.
+	cache sync.Map
+}
+
+// parseLevelOverrides parses the spec like "billing/*=debug,github.com/foo/*=warn".
+func parseLevelOverrides(spec string) (*levelOverrides, error) {
+	o := &levelOverrides{}
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		i := strings.LastIndex(s, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("log: invalid level override %q: missing '='", s)
+		}
+		pattern := strings.TrimSpace(s[:i])
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("log: invalid level override %q: bad pattern", s)
+		}
+		lv, err := level.Parse(s[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("log: invalid level override %q: %v", s, err)
+		}
+
+		o.rules = append(o.rules, levelOverride{pattern: pattern, level: lv})
+		if o.min == "" || lv.LessThan(o.min) {
+			o.min = lv
+		}
+		if o.max == "" || o.max.LessThan(lv) {
+			o.max = lv
+		}
+	}
+	if len(o.rules) == 0 {
+		return nil, nil
+	}
+	return o, nil
+}
+
+// enabled reports whether lv is enabled for the caller when the level of the logger is def.
+// The caller is looked up with skip only if the rules can change the result.
+func (o *levelOverrides) enabled(lv, def level.Level, skip int) bool {
+	if !lv.LessThan(def) && !lv.LessThan(o.max) {
+		return true
+	}
+	if lv.LessThan(def) && lv.LessThan(o.min) {
+		return false
+	}
+	return !lv.LessThan(o.levelAt(callerPC(skip), def))
+}
+
+// levelAt returns the minimum logging level for the caller at pc.
+func (o *levelOverrides) levelAt(pc uintptr, def level.Level) level.Level {
+	if pc == 0 {
+		return def
+	}
+	var lv level.Level
+	if v, ok := o.cache.Load(pc); ok {
+		lv = v.(level.Level)
+	} else {
+		lv = o.match(frameOf(pc).pkg)
+		o.cache.Store(pc, lv)
+	}
+	if lv == "" {
+		return def
+	}
+	return lv
+}
+
+// match returns the level of the first rule whose pattern matches pkg.
+func (o *levelOverrides) match(pkg string) level.Level {
+	for _, r := range o.rules {
+		if matchPackage(r.pattern, pkg) {
+			return r.level
+		}
+	}
+	return ""
+}
+
+// matchPackage reports whether pattern matches the import path pkg or its suffix after "/".
+// The pattern ending with "/..." matches the package and all the packages under it,
+// and the one ending with "/*" matches the package and the packages right under it.
+func matchPackage(pattern, pkg string) bool {
+	if prefix := strings.TrimSuffix(pattern, "/*"); prefix != pattern && matchPackage(prefix, pkg) {
+		return true
+	}
+	if prefix := strings.TrimSuffix(pattern, "/..."); prefix != pattern {
+		for p := pkg; p != "." && p != "/"; p = path.Dir(p) {
+			if matchPackage(prefix, p) {
+				return true
+			}
+		}
+		return false
+	}
+	for {
+		if ok, _ := path.Match(pattern, pkg); ok {
+			return true
+		}
+		i := strings.Index(pkg, "/")
+		if i < 0 {
+			return false
+		}
+		pkg = pkg[i+1:]
+	}
+}
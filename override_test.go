@@ -0,0 +1,141 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevelOverrides(t *testing.T) {
+	assert := assert.New(t)
+
+	o, err := parseLevelOverrides(" billing/*=debug, github.com/foo/...=WARN ,")
+	assert.NoError(err)
+	assert.Equal([]levelOverride{
+		{pattern: "billing/*", level: level.Debug},
+		{pattern: "github.com/foo/...", level: level.Warn},
+	}, o.rules)
+	assert.Equal(level.Debug, o.min)
+	assert.Equal(level.Warn, o.max)
+
+	o, err = parseLevelOverrides("")
+	assert.NoError(err)
+	assert.Nil(o)
+
+	for _, spec := range []string{"billing", "=debug", "billing=extream", "[=debug"} {
+		_, err := parseLevelOverrides(spec)
+		assert.Error(err, spec)
+	}
+}
+
+func TestMatchPackage(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		pattern, pkg string
+		want         bool
+	}{
+		{"billing", "github.com/acme/internal/billing", true},
+		{"internal/billing", "github.com/acme/internal/billing", true},
+		{"billing", "github.com/acme/internal/billing/invoice", false},
+		{"billing/*", "github.com/acme/internal/billing/invoice", true},
+		{"billing/*", "github.com/acme/internal/billing", true},
+		{"billing/*", "github.com/acme/internal/billingx", false},
+		{"github.com/foo/*", "github.com/foo", true},
+		{"github.com/foo/*", "github.com/foo/bar", true},
+		{"github.com/foo/*", "github.com/foo/bar/baz", false},
+		{"github.com/foo/...", "github.com/foo", true},
+		{"github.com/foo/...", "github.com/foo/bar/baz", true},
+		{"github.com/foo/...", "github.com/foobar", false},
+		{"main", "main", true},
+	} {
+		assert.Equal(tc.want, matchPackage(tc.pattern, tc.pkg), "%s %s", tc.pattern, tc.pkg)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("github.com/kyfk/log", funcPackage("github.com/kyfk/log.(*Logger).Info"))
+	assert.Equal("github.com/kyfk/log", funcPackage("github.com/kyfk/log.TestFuncPackage.func1"))
+	assert.Equal("main", funcPackage("main.main"))
+
+	t.Run("the dots escaped in the last element are unescaped", func(t *testing.T) {
+		pkg := funcPackage("gopkg.in/yaml%2ev3.(*Decoder).Decode")
+		assert.Equal("gopkg.in/yaml.v3", pkg)
+		assert.Equal("gopkg.in/yaml.v3", funcPackage("gopkg.in/yaml%2ev3.Unmarshal"))
+
+		o, err := parseLevelOverrides("yaml.v3=debug")
+		assert.NoError(err)
+		assert.Equal(level.Debug, o.match(pkg))
+		assert.Equal("gopkg.in/yaml.v3/decode.go", trimPath("/go/pkg/mod/gopkg.in/yaml.v3@v3.0.1/decode.go", pkg))
+	})
+}
+
+func TestLevelOverrides(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		MinLevel(level.Info),
+		LevelOverrides("kyfk/log=debug"),
+		Format(format.Logfmt),
+		Output(buf),
+	)
+
+	t.Run("lower the level for the matched caller", func(t *testing.T) {
+		buf.Reset()
+		logger.Debug("debug")
+		logger.DebugFields("debug fields")
+		assert.Contains(buf.String(), "message=debug\n")
+		assert.Contains(buf.String(), `message="debug fields"`)
+	})
+
+	t.Run("the level of the logger is used for the other callers", func(t *testing.T) {
+		buf.Reset()
+		logger.SetLevelOverrides("github.com/other/...=trace")
+		logger.Debug("debug")
+		assert.Empty(buf.String())
+	})
+
+	t.Run("raise the level for the matched caller", func(t *testing.T) {
+		buf.Reset()
+		logger.SetLevelOverrides("github.com/kyfk/...=error")
+		logger.Info("info")
+		logger.Warn("warn")
+		assert.Empty(buf.String())
+	})
+
+	t.Run("invalid spec doesn't change the overrides", func(t *testing.T) {
+		buf.Reset()
+		logger.SetLevelOverrides("invalid")
+		logger.Warn("warn")
+		assert.Empty(buf.String())
+	})
+
+	t.Run("empty spec removes the overrides", func(t *testing.T) {
+		buf.Reset()
+		logger.SetLevelOverrides("")
+		logger.Info("info")
+		assert.Contains(buf.String(), "message=info")
+	})
+}
+
+func TestLevelOverridesOfDefaultLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	SetMinLevel(level.Info)
+	SetFormat(format.Logfmt)
+	SetOutput(buf)
+	SetLevelOverrides("kyfk/log=debug")
+	defer func() {
+		SetLevelOverrides("")
+		SetMinLevel(level.Debug)
+	}()
+
+	Debug("debug")
+	assert.Contains(buf.String(), "message=debug")
+}
@@ -0,0 +1,73 @@
+package rotate
+
+import (
+	"os"
+	"time"
+)
+
+type options struct {
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	signals    []os.Signal
+
+	// this field is only for testing
+	nowFunc func() time.Time
+}
+
+// Option is a function for initialization in the constructor of File.
+type Option func(options) options
+
+// MaxSize returns Option that rotates the file when its size exceeds n bytes.
+func MaxSize(n int64) Option {
+	return func(o options) options {
+		o.maxSize = n
+		return o
+	}
+}
+
+// Interval returns Option that rotates the file every d.
+// The rotation happens at the multiples of d since the zero time,
+// for instance, every hour on the hour if d is time.Hour.
+func Interval(d time.Duration) Option {
+	return func(o options) options {
+		o.interval = d
+		return o
+	}
+}
+
+// MaxBackups returns Option that keeps at most n rotated files.
+// The older files are removed.
+func MaxBackups(n int) Option {
+	return func(o options) options {
+		o.maxBackups = n
+		return o
+	}
+}
+
+// MaxAge returns Option that removes the rotated files older than d.
+func MaxAge(d time.Duration) Option {
+	return func(o options) options {
+		o.maxAge = d
+		return o
+	}
+}
+
+// Compress returns Option that sets the flag if rotated files are gzipped in the background.
+func Compress(b bool) Option {
+	return func(o options) options {
+		o.compress = b
+		return o
+	}
+}
+
+// ReopenOnSignal returns Option that reopens the file when the process receives one of sigs.
+// It is used for compatibility with logrotate, which sends SIGHUP after moving the file.
+func ReopenOnSignal(sigs ...os.Signal) Option {
+	return func(o options) options {
+		o.signals = append(append([]os.Signal(nil), o.signals...), sigs...)
+		return o
+	}
+}
@@ -0,0 +1,397 @@
+// Package rotate provides a file output that rotates itself.
+//
+// File implements io.WriteCloser, so it can be set as the output of a logger.
+//
+//	f, err := rotate.New("/var/log/app.log",
+//		rotate.MaxSize(100<<20),
+//		rotate.MaxBackups(7),
+//		rotate.Compress(true),
+//		rotate.ReopenOnSignal(syscall.SIGHUP),
+//	)
+//	if err != nil {
+//		// handle error
+//	}
+//	defer f.Close()
+//	logger := log.New(log.Output(f))
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the format of the time in the name of rotated files.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+const compressSuffix = ".gz"
+
+// File is an io.WriteCloser that writes to a file and rotates it
+// along the options.
+// File is safe for concurrent use by multiple goroutines.
+type File struct {
+	filename string
+	opts     options
+
+	mu       sync.Mutex
+	file     *os.File // nil after the rotation or the reopen failed, then Write opens it again
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	millCh chan struct{}
+	sigCh  chan os.Signal
+	wg     sync.WaitGroup
+}
+
+// New opens the file of filename for appending and returns File that writes to it.
+// The directory of the file is created if it doesn't exist.
+func New(filename string, ops ...Option) (*File, error) {
+	o := options{nowFunc: time.Now}
+	for _, op := range ops {
+		o = op(o)
+	}
+
+	f := &File{
+		filename: filename,
+		opts:     o,
+		millCh:   make(chan struct{}, 1),
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	f.wg.Add(1)
+	go f.mill()
+
+	if len(o.signals) > 0 {
+		f.sigCh = make(chan os.Signal, 1)
+		signal.Notify(f.sigCh, o.signals...)
+		f.wg.Add(1)
+		go f.handleSignals()
+	}
+	return f, nil
+}
+
+// Write writes p to the file.
+// If writing p exceeds the max size or the rotation interval has passed,
+// the file is rotated before writing.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file regardless of the options.
+func (f *File) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Reopen closes the file and opens the file of the same name again.
+// It is used after an external tool such as logrotate moved the file.
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if err := f.closeFile(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Close closes the file and waits until the rotated files are compressed and removed.
+func (f *File) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return os.ErrClosed
+	}
+	f.closed = true
+	err := f.closeFile()
+	f.mu.Unlock()
+
+	if f.sigCh != nil {
+		signal.Stop(f.sigCh)
+		close(f.sigCh)
+	}
+	close(f.millCh)
+	f.wg.Wait()
+	return err
+}
+
+func (f *File) shouldRotate(n int64) bool {
+	if f.opts.maxSize > 0 && f.size > 0 && f.size+n > f.opts.maxSize {
+		return true
+	}
+	if f.opts.interval > 0 {
+		next := f.openedAt.Truncate(f.opts.interval).Add(f.opts.interval)
+		if !f.opts.nowFunc().Before(next) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.opts.nowFunc()
+	return nil
+}
+
+// closeFile closes the file and clears it,
+// so that the next Write opens the file again even if the rotation or the reopen fails after this.
+func (f *File) closeFile() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *File) rotate() error {
+	if err := f.closeFile(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.filename, f.backupName(f.opts.nowFunc())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	select {
+	case f.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (f *File) handleSignals() {
+	defer f.wg.Done()
+	for range f.sigCh {
+		if err := f.Reopen(); err != nil {
+			fmt.Fprintf(os.Stderr, "rotate: failed to reopen %s: %v\n", f.filename, err)
+		}
+	}
+}
+
+// mill compresses and removes the rotated files in the background.
+func (f *File) mill() {
+	defer f.wg.Done()
+	for range f.millCh {
+		if err := f.millOnce(); err != nil {
+			fmt.Fprintf(os.Stderr, "rotate: failed to process rotated files of %s: %v\n", f.filename, err)
+		}
+	}
+}
+
+func (f *File) millOnce() error {
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []backup
+	if f.opts.maxBackups > 0 && len(backups) > f.opts.maxBackups {
+		remove = append(remove, backups[f.opts.maxBackups:]...)
+		backups = backups[:f.opts.maxBackups]
+	}
+	if f.opts.maxAge > 0 {
+		cutoff := f.opts.nowFunc().Add(-f.opts.maxAge)
+		for i, b := range backups {
+			if b.time.Before(cutoff) {
+				remove = append(remove, backups[i:]...)
+				backups = backups[:i]
+				break
+			}
+		}
+	}
+
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if f.opts.compress {
+		for _, b := range backups {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+			if err := compress(b.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *File) prefixAndExt() (string, string) {
+	base := filepath.Base(f.filename)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// backupName returns the name of the rotated file at t.
+// If the file of the name already exists, for the rotations in the same millisecond,
+// the sequence number like "-1" is added to the time so that the file isn't overwritten.
+func (f *File) backupName(t time.Time) string {
+	prefix, ext := f.prefixAndExt()
+	stamp := filepath.Join(filepath.Dir(f.filename), prefix+t.UTC().Format(backupTimeFormat))
+	name := stamp + ext
+	for seq := 1; exists(name) || exists(name+compressSuffix); seq++ {
+		name = stamp + "-" + strconv.Itoa(seq) + ext
+	}
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+type backup struct {
+	path string
+	time time.Time
+	seq  int // the sequence number of the rotated files at the same time
+}
+
+// parseBackupStamp parses the time and the sequence number in the name of a rotated file.
+func parseBackupStamp(s string) (time.Time, int, bool) {
+	if len(s) < len(backupTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(backupTimeFormat, s[:len(backupTimeFormat)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	s = s[len(backupTimeFormat):]
+	if s == "" {
+		return t, 0, true
+	}
+	if s[0] != '-' {
+		return time.Time{}, 0, false
+	}
+	seq, err := strconv.Atoi(s[1:])
+	if err != nil || seq <= 0 {
+		return time.Time{}, 0, false
+	}
+	return t, seq, true
+}
+
+// backups returns the rotated files sorted from newest to oldest.
+func (f *File) backups() ([]backup, error) {
+	dir := filepath.Dir(f.filename)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, ext := f.prefixAndExt()
+	var backups []backup
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(info.Name(), compressSuffix)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		t, seq, ok := parseBackupStamp(name[len(prefix) : len(name)-len(ext)])
+		if !ok {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, info.Name()), time: t, seq: seq})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	return backups, nil
+}
+
+// compress gzips the file of path into path.gz and removes the file.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,179 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is the clock for testing, which is read by the background goroutine as well.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// add advances the clock by d and returns the time.
+func (c *clock) add(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// option returns Option that replaces the clock of File with c.
+func (c *clock) option() Option {
+	return func(o options) options {
+		o.nowFunc = func() time.Time { return c.add(0) }
+		return o
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMaxSize(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	clk := &clock{now: time.Date(2019, 10, 22, 0, 0, 0, 0, time.UTC)}
+	f, err := New(filepath.Join(dir, "app.log"), MaxSize(10), clk.option())
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("0123456789"))
+	assert.NoError(err)
+	clk.add(time.Second)
+	_, err = f.Write([]byte("abc"))
+	assert.NoError(err)
+	assert.NoError(f.Close())
+
+	assert.Equal([]string{"app-2019-10-22T00-00-01.000.log", "app.log"}, listDir(t, dir))
+	b, err := ioutil.ReadFile(filepath.Join(dir, "app-2019-10-22T00-00-01.000.log"))
+	assert.NoError(err)
+	assert.Equal("0123456789", string(b))
+	b, err = ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NoError(err)
+	assert.Equal("abc", string(b))
+}
+
+func TestInterval(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	clk := &clock{now: time.Date(2019, 10, 22, 0, 30, 0, 0, time.UTC)}
+	f, err := New(filepath.Join(dir, "app.log"), Interval(time.Hour), clk.option())
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("a"))
+	assert.NoError(err)
+	clk.add(20 * time.Minute)
+	_, err = f.Write([]byte("b"))
+	assert.NoError(err)
+	assert.Equal([]string{"app.log"}, listDir(t, dir))
+
+	clk.add(20 * time.Minute)
+	_, err = f.Write([]byte("c"))
+	assert.NoError(err)
+	assert.NoError(f.Close())
+
+	assert.Equal([]string{"app-2019-10-22T01-10-00.000.log", "app.log"}, listDir(t, dir))
+}
+
+func TestRetention(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	clk := &clock{now: time.Date(2019, 10, 22, 0, 0, 0, 0, time.UTC)}
+	f, err := New(filepath.Join(dir, "app.log"), MaxBackups(2), MaxAge(time.Hour), Compress(true), clk.option())
+	require.NoError(t, err)
+
+	for _, d := range []time.Duration{0, 2 * time.Hour, time.Minute, time.Minute} {
+		now := clk.add(d)
+		_, err = f.Write([]byte(now.Format(time.RFC3339)))
+		assert.NoError(err)
+		assert.NoError(f.Rotate())
+	}
+	assert.NoError(f.Close())
+
+	assert.Equal([]string{
+		"app-2019-10-22T02-01-00.000.log.gz",
+		"app-2019-10-22T02-02-00.000.log.gz",
+		"app.log",
+	}, listDir(t, dir))
+
+	gf, err := os.Open(filepath.Join(dir, "app-2019-10-22T02-02-00.000.log.gz"))
+	require.NoError(t, err)
+	defer gf.Close()
+	gz, err := gzip.NewReader(gf)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(gz)
+	assert.NoError(err)
+	assert.Equal("2019-10-22T02:02:00Z", string(b))
+}
+
+func TestRotateInSameMillisecond(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	clk := &clock{now: time.Date(2019, 10, 22, 0, 0, 0, 0, time.UTC)}
+	f, err := New(filepath.Join(dir, "app.log"), MaxBackups(2), clk.option())
+	require.NoError(t, err)
+
+	for _, s := range []string{"1", "2", "3"} {
+		_, err = f.Write([]byte(s))
+		assert.NoError(err)
+		assert.NoError(f.Rotate())
+	}
+	assert.NoError(f.Close())
+
+	// the oldest one is removed along the sequence numbers.
+	assert.Equal([]string{
+		"app-2019-10-22T00-00-00.000-1.log",
+		"app-2019-10-22T00-00-00.000-2.log",
+		"app.log",
+	}, listDir(t, dir))
+	for name, want := range map[string]string{
+		"app-2019-10-22T00-00-00.000-1.log": "2",
+		"app-2019-10-22T00-00-00.000-2.log": "3",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NoError(err)
+		assert.Equal(want, string(b))
+	}
+}
+
+func TestClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := New(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.NoError(t, f.Close())
+	_, err = f.Write([]byte("a"))
+	assert.Equal(t, os.ErrClosed, err)
+	assert.Equal(t, os.ErrClosed, f.Close())
+}
@@ -0,0 +1,82 @@
+//go:build !windows
+// +build !windows
+
+package rotate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReopenOnSignal(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "app.log")
+	f, err := New(name, ReopenOnSignal(syscall.SIGHUP))
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("a"))
+	assert.NoError(err)
+	require.NoError(t, os.Rename(name, name+".1"))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	assert.Eventually(func() bool {
+		_, err := os.Stat(name)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = f.Write([]byte("b"))
+	assert.NoError(err)
+	b, err := ioutil.ReadFile(name)
+	assert.NoError(err)
+	assert.Equal("b", string(b))
+}
+
+func TestRecoverFromFailure(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub")
+	name := filepath.Join(sub, "app.log")
+	f, err := New(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	// breakDir replaces the directory of the file with a regular file,
+	// so that the file can't be renamed nor opened.
+	breakDir := func() {
+		require.NoError(t, os.RemoveAll(sub))
+		require.NoError(t, ioutil.WriteFile(sub, nil, 0644))
+	}
+	fixDir := func() {
+		require.NoError(t, os.Remove(sub))
+	}
+
+	for _, fail := range []func() error{f.Rotate, f.Reopen} {
+		breakDir()
+		assert.Error(fail())
+		_, err = f.Write([]byte("a"))
+		assert.Error(err)
+		assert.NotEqual(os.ErrClosed, err)
+
+		fixDir()
+		_, err = f.Write([]byte("b"))
+		assert.NoError(err)
+		b, err := ioutil.ReadFile(name)
+		assert.NoError(err)
+		assert.Equal("b", string(b))
+	}
+}
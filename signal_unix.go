@@ -0,0 +1,52 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// HandleLevelSignals changes the minimum logging level of l when the process receives signals.
+// If l is nil, the default logger is used.
+//
+// SIGUSR1 lowers the level to the next more verbose level, and it is cycled back to the level
+// at the time of the call after the most verbose level. SIGUSR2 resets the level to the one
+// at the time of the call. If revertAfter is positive, the level changed by SIGUSR1 is
+// reverted after the duration as SetMinLevelFor does.
+//
+// The returned function stops handling the signals.
+func HandleLevelSignals(l *Logger, revertAfter time.Duration) (stop func()) {
+	if l == nil {
+		l = defaultLogger
+	}
+	base := l.Level()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for sig := range sigCh {
+			switch sig {
+			case syscall.SIGUSR1:
+				l.SetMinLevelFor(nextLevel(l.Level(), base), revertAfter)
+			case syscall.SIGUSR2:
+				l.SetMinLevel(base)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigCh)
+			close(sigCh)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	assert := assert.New(t)
+
+	logger := New(MinLevel(level.Info))
+	stop := HandleLevelSignals(logger, 0)
+	defer stop()
+
+	signal := func(sig syscall.Signal, want level.Level) {
+		assert.NoError(syscall.Kill(syscall.Getpid(), sig))
+		assert.Equal(want, waitForLevel(logger, want))
+	}
+
+	signal(syscall.SIGUSR1, level.Debug)
+	signal(syscall.SIGUSR1, level.Trace)
+	signal(syscall.SIGUSR1, level.Info)
+	signal(syscall.SIGUSR1, level.Debug)
+	signal(syscall.SIGUSR2, level.Info)
+
+	stop()
+	stop()
+}
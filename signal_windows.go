@@ -0,0 +1,9 @@
+package log
+
+import "time"
+
+// HandleLevelSignals does nothing on Windows, which has neither SIGUSR1 nor SIGUSR2.
+// See the documentation on the other platforms for the details.
+func HandleLevelSignals(l *Logger, revertAfter time.Duration) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"io"
+	"log"
+
+	"github.com/kyfk/log/level"
+)
+
+// Sink is a destination of logging message.
+type Sink struct {
+	// Writer is the destination of logging message.
+	Writer io.Writer
+	// MinLevel is the minimum logging level of the sink.
+	// If it is empty, all messages that the logger outputs are written.
+	// If it isn't registered, it is written to stderr and treated as empty.
+	MinLevel level.Level
+	// Formatter is the format of message output to the sink.
+	// If both of it and Layout are nil, the format of the logger is used.
+	Formatter Formatter
+	// Layout builds the format of message output to the sink, which is used instead of Formatter.
+	Layout Layout
+}
+
+type sink struct {
+	out       *log.Logger
+	minLevel  level.Level
+	formatter Formatter
+	layout    Layout
+
+	// format is the formatter resolved by prepare, which is used to output messages.
+	format Formatter
+}
+
+// resolveFormat returns the formatter of the sink
+// and whether the sink outputs in the same way as format.JSON by JSONLayout.
+// The layout is built with the names of the logger and the writer of the sink.
+func (c *config) resolveFormat(sk sink) (Formatter, bool) {
+	ly, fm := sk.layout, sk.formatter
+	if ly == nil && fm == nil {
+		ly, fm = c.layout, c.formatter
+	}
+	if ly == nil {
+		return fm, false
+	}
+	_, isJSON := ly.(jsonLayout)
+	return ly.Formatter(c.names, sk.out.Writer()), isJSON
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputs(t *testing.T) {
+	assert := assert.New(t)
+
+	console := bytes.NewBuffer(nil)
+	file := bytes.NewBuffer(nil)
+	logger := New(
+		Format(format.JSONPretty),
+		Outputs(
+			Sink{Writer: console, MinLevel: level.Debug},
+			Sink{Writer: file, MinLevel: level.Info, Formatter: format.JSON},
+		),
+		nowFunc(func() time.Time { return time.Time{} }),
+	)
+
+	t.Run("the message is written to the sinks of lower minimum level", func(t *testing.T) {
+		logger.Debug("debug")
+		assert.Equal(`{
+  "level": "DEBUG",
+  "message": "debug",
+  "meta": {},
+  "time": "0001-01-01T00:00:00Z"
+}
+`, console.String())
+		assert.Empty(file.String())
+	})
+
+	console.Reset()
+
+	t.Run("the message is written to all the sinks with their formats", func(t *testing.T) {
+		logger.Info("info")
+		assert.Equal(`{
+  "level": "INFO",
+  "message": "info",
+  "meta": {},
+  "time": "0001-01-01T00:00:00Z"
+}
+`, console.String())
+		assert.Equal(`{"level":"INFO","message":"info","meta":{},"time":"0001-01-01T00:00:00Z"}
+`, file.String())
+	})
+
+	t.Run("the unregistered minimum level of a sink is reported", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		var lg *Logger
+		stderr := captureStderr(t, func() {
+			lg = New(Outputs(Sink{Writer: buf, MinLevel: level.Level("WARNING")}), MinLevel(level.Debug))
+		})
+		assert.Equal("log: unregistered level \"WARNING\" is ignored\n", stderr)
+		assert.Equal(level.Level(""), lg.load().sinks[0].minLevel)
+		lg.Debug("debug")
+		assert.NotEmpty(buf.String())
+	})
+
+	t.Run("the failure of a sink doesn't stop the other sinks", func(t *testing.T) {
+		jsonBuf := bytes.NewBuffer(nil)
+		logfmtBuf := bytes.NewBuffer(nil)
+		lg := New(
+			Outputs(
+				Sink{Writer: jsonBuf, Formatter: format.JSON},
+				Sink{Writer: logfmtBuf, Formatter: format.Logfmt},
+			),
+			nowFunc(func() time.Time { return time.Time{} }),
+			withoutTrace(true),
+		)
+
+		lg.InfoFields("info", Float64("nan", math.NaN()))
+		assert.Equal("level=INFO time=0001-01-01T00:00:00Z message=info nan=NaN\n", firstLine(logfmtBuf.String()))
+		assert.Contains(jsonBuf.String(), "json: unsupported value: NaN")
+		assert.NotContains(jsonBuf.String(), `"message":"info"`)
+	})
+
+	t.Run("Output replaces the sinks", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		logger.SetOutput(buf)
+		assert.Len(logger.load().sinks, 1)
+		logger.Info("info")
+		assert.NotEmpty(buf.String())
+	})
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i+1]
+	}
+	return s
+}
@@ -0,0 +1,314 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	"github.com/kyfk/log/level"
+)
+
+// SlogHandler is slog.Handler that outputs records through Logger,
+// so that the log/slog API outputs in the same way as Logger.
+// The attributes added by WithAttrs are output as metadata nested in the groups,
+// and the attributes of records are output as fields nested in the groups.
+// The error of the attribute "error" out of groups is output as the error of the entry.
+type SlogHandler struct {
+	logger *Logger
+	groups []string
+
+	// attrs is the attributes added by WithAttrs nested in the groups,
+	// which are merged into the current metadata of the logger on output.
+	// The configuration with them is cached in derived.
+	attrs   map[string]interface{}
+	derived atomic.Value // *derivedConfig
+}
+
+// NewSlogHandler returns SlogHandler that outputs records through l.
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{logger: l}
+}
+
+// Enabled reports whether the minimum level of the logger enables lv.
+// If the level overrides are set, the level is checked again with the caller in Handle.
+func (h *SlogHandler) Enabled(_ context.Context, lv slog.Level) bool {
+	c := h.logger.load()
+	min := c.level
+	if c.overrides != nil && c.overrides.min.LessThan(min) {
+		min = c.overrides.min
+	}
+	return !fromSlogLevel(lv).LessThan(min)
+}
+
+// Handle outputs the record.
+// The records of level Warn and Error have the stack trace from the caller of the record.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	c := h.load()
+	if c.overrides != nil {
+		if fromSlogLevel(r.Level).LessThan(c.overrides.levelAt(r.PC, c.level)) {
+			return nil
+		}
+	}
+	c = c.withContext(ctx)
+
+	e := &Entry{
+		Level:   fromSlogLevel(r.Level),
+		Time:    r.Time,
+		Message: r.Message,
+	}
+	if e.Time.IsZero() {
+		e.Time = c.nowFunc()
+	}
+
+	fields := make(map[string]interface{}, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		if len(h.groups) == 0 && a.Key == "error" {
+			if err, ok := a.Value.Resolve().Any().(error); ok {
+				e.Error = err
+				return true
+			}
+		}
+		addAttr(fields, a)
+		return true
+	})
+	if len(fields) > 0 {
+		e.Fields = nest(h.groups, fields)
+	}
+
+	if c.captures(e.Level) {
+		e.Trace = c.captureTrace(r.PC)
+	}
+	if c.callerMode != CallerOff {
+		e.Caller = callerOf(r.PC, c.callerMode)
+	}
+
+	h.logger.output(c, e)
+	return nil
+}
+
+// load returns the configuration of the logger whose metadata is merged with the attributes.
+// It is rebuilt only when the configuration of the logger is changed.
+func (h *SlogHandler) load() *config {
+	base := h.logger.load()
+	if len(h.attrs) == 0 {
+		return base
+	}
+	if d, ok := h.derived.Load().(*derivedConfig); ok && d.base == base {
+		return d.c
+	}
+	fields := make(map[string]interface{}, len(h.attrs))
+	for k, v := range h.attrs {
+		fields[k] = mergeGroup(base.metadata[k], v)
+	}
+	c := base.withMetadata(fields)
+	h.derived.Store(&derivedConfig{base: base, c: c})
+	return c
+}
+
+// WithAttrs returns SlogHandler that outputs the metadata of the logger extended with attrs.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(map[string]interface{}, len(attrs))
+	for _, a := range attrs {
+		addAttr(fields, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+
+	fields = nest(h.groups, fields)
+	for k, v := range fields {
+		fields[k] = mergeGroup(h.attrs[k], v)
+	}
+	return &SlogHandler{logger: h.logger, groups: h.groups, attrs: extend(h.attrs, fields)}
+}
+
+// WithGroup returns SlogHandler that nests the attributes added after in the group.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := make([]string, len(h.groups)+1)
+	copy(groups, h.groups)
+	groups[len(h.groups)] = name
+	return &SlogHandler{logger: h.logger, groups: groups, attrs: h.attrs}
+}
+
+func fromSlogLevel(lv slog.Level) level.Level {
+	switch {
+	case lv < slog.LevelDebug:
+		return level.Trace
+	case lv < slog.LevelInfo:
+		return level.Debug
+	case lv < slog.LevelWarn:
+		return level.Info
+	case lv < slog.LevelError:
+		return level.Warn
+	default:
+		return level.Error
+	}
+}
+
+func toSlogLevel(lv level.Level) slog.Level {
+	// custom levels are mapped to the built-in level just below them.
+	switch {
+	case lv.LessThan(level.Debug):
+		return slog.LevelDebug - 4
+	case lv.LessThan(level.Info):
+		return slog.LevelDebug
+	case lv.LessThan(level.Warn):
+		return slog.LevelInfo
+	case lv.LessThan(level.Error):
+		return slog.LevelWarn
+	case lv.LessThan(level.Panic):
+		return slog.LevelError
+	case lv.LessThan(level.Fatal):
+		return slog.LevelError + 4
+	default:
+		return slog.LevelError + 8
+	}
+}
+
+// addAttr adds the attribute to m along the rules of slog.Handler.
+func addAttr(m map[string]interface{}, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		m[a.Key] = slogValue(a.Value)
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	if a.Key == "" {
+		for _, ga := range attrs {
+			addAttr(m, ga)
+		}
+		return
+	}
+	g, ok := m[a.Key].(map[string]interface{})
+	if !ok {
+		g = make(map[string]interface{}, len(attrs))
+		m[a.Key] = g
+	}
+	for _, ga := range attrs {
+		addAttr(g, ga)
+	}
+}
+
+func slogValue(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time()
+	default:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
+	}
+}
+
+// nest returns m nested in the groups.
+func nest(groups []string, m map[string]interface{}) map[string]interface{} {
+	for i := len(groups) - 1; i >= 0; i-- {
+		m = map[string]interface{}{groups[i]: m}
+	}
+	return m
+}
+
+// mergeGroup returns the group b merged into the group a.
+// If either isn't a group, b is returned.
+func mergeGroup(a, b interface{}) interface{} {
+	am, ok := a.(map[string]interface{})
+	if !ok {
+		return b
+	}
+	bm, ok := b.(map[string]interface{})
+	if !ok {
+		return b
+	}
+	m := extend(am, nil)
+	for k, v := range bm {
+		m[k] = mergeGroup(am[k], v)
+	}
+	return m
+}
+
+// SlogOutput returns Option that makes a new logger forward entries to h
+// instead of writing them to the outputs.
+// The fields, the metadata, the error and the trace of entries are converted to attributes.
+// If metadata isn't flattened, it is converted to the group named "meta" or the name set by FieldNames.
+// The level, the time and the message are passed as the ones of the records, which are named by h.
+func SlogOutput(h slog.Handler) Option {
+	return func(c config) config {
+		c.slogHandler = h
+		return c
+	}
+}
+
+// forward converts the entry to slog.Record and passes it to the handler.
+func forward(c *config, e *Entry) {
+	ctx := context.Background()
+	lv := toSlogLevel(e.Level)
+	if !c.slogHandler.Enabled(ctx, lv) {
+		return
+	}
+
+	r := slog.NewRecord(e.Time, lv, e.Message, 0)
+	if e.Error != nil {
+		r.AddAttrs(slog.Any(c.names.Error, errorValue(e.Error)))
+	}
+	if e.Trace != nil {
+		r.AddAttrs(slog.Any(c.names.Trace, e.Trace))
+	}
+	if e.Caller != nil {
+		r.AddAttrs(slog.Any(c.names.Caller, e.Caller))
+	}
+	for k, v := range e.Fields {
+		if !isReservedKey(&c.names, k) {
+			r.AddAttrs(slog.Any(k, v))
+		}
+	}
+	if c.flattenMetadata {
+		for _, k := range c.metadataKeys {
+			_, ok := e.Fields[k]
+			if !ok && !(isReservedKey(&c.names, k) && e.has(&c.names, k)) {
+				r.AddAttrs(slog.Any(k, c.flatMetadata[k]))
+				continue
+			}
+			switch c.metadataConflict {
+			case ConflictMetadataWins:
+				r.AddAttrs(slog.Any(k, c.flatMetadata[k]))
+			case ConflictRename:
+				r.AddAttrs(slog.Any(c.conflictPrefix+k, c.flatMetadata[k]))
+			}
+		}
+	} else if len(c.metadata) > 0 {
+		attrs := make([]interface{}, 0, len(c.metadata))
+		for _, k := range c.metadataKeys {
+			attrs = append(attrs, slog.Any(k, c.metadata[k]))
+		}
+		r.AddAttrs(slog.Group(c.names.Metadata, attrs...))
+	}
+
+	if err := c.slogHandler.Handle(ctx, r); err != nil {
+		fmt.Fprintf(os.Stderr, "log: failed to forward an entry to slog.Handler: %v\n", err)
+	}
+}
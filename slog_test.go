@@ -0,0 +1,130 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(
+		Format(format.JSON),
+		Output(buf),
+		MinLevel(level.Info),
+		Metadata(map[string]interface{}{"service": "book"}),
+	)
+	sl := slog.New(NewSlogHandler(logger))
+
+	t.Run("records are output through the logger", func(t *testing.T) {
+		buf.Reset()
+		sl.Info("info", "request_id", "req1", slog.Int("count", 1))
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("INFO", mp["level"])
+		assert.Equal("info", mp["message"])
+		assert.Equal("req1", mp["request_id"])
+		assert.Equal(float64(1), mp["count"])
+		assert.Equal(map[string]interface{}{"service": "book"}, mp["meta"])
+		assert.Nil(mp["trace"])
+	})
+
+	t.Run("the minimum level is honored", func(t *testing.T) {
+		buf.Reset()
+		sl.Debug("debug")
+		assert.Empty(buf.String())
+		assert.False(sl.Enabled(context.Background(), slog.LevelDebug))
+	})
+
+	t.Run("WithAttrs and WithGroup are mapped to nested metadata", func(t *testing.T) {
+		buf.Reset()
+		sl.With("user_id", "user1").
+			WithGroup("http").With("method", "GET").
+			WithGroup("request").With("path", "/").
+			With(slog.Group("", "size", 10)).
+			Info("info", "status", 200)
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal(map[string]interface{}{
+			"service": "book",
+			"user_id": "user1",
+			"http": map[string]interface{}{
+				"method": "GET",
+				"request": map[string]interface{}{
+					"path": "/",
+					"size": float64(10),
+				},
+			},
+		}, mp["meta"])
+		assert.Equal(map[string]interface{}{
+			"request": map[string]interface{}{"status": float64(200)},
+		}, mp["http"])
+	})
+
+	t.Run("the metadata set to the logger after WithAttrs is merged", func(t *testing.T) {
+		lg := New(Format(format.JSON), Output(buf), Metadata(map[string]interface{}{"service": "book"}))
+		sl := slog.New(NewSlogHandler(lg)).WithGroup("http").With("method", "GET")
+
+		lg.SetMetadata(map[string]interface{}{
+			"service": "shelf",
+			"http":    map[string]interface{}{"host": "example.com", "method": "POST"},
+		})
+		buf.Reset()
+		sl.Info("info")
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal(map[string]interface{}{
+			"service": "shelf",
+			"http":    map[string]interface{}{"host": "example.com", "method": "GET"},
+		}, mp["meta"])
+	})
+
+	t.Run("records of level Warn have the trace from the caller", func(t *testing.T) {
+		buf.Reset()
+		sl.Warn("warn", "error", errors.New("error"))
+		var mp map[string]interface{}
+		assert.NoError(json.Unmarshal(buf.Bytes(), &mp))
+		assert.Equal("WARN", mp["level"])
+		assert.Equal("warn", mp["message"])
+		assert.Equal(errorJSON("*errors.errorString", "error"), mp["error"])
+		trace := mp["trace"].([]interface{})
+		assert.True(strings.HasPrefix(trace[0].(string), "github.com/kyfk/log.TestSlogHandler.func"), trace[0])
+	})
+}
+
+func TestSlogOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	h := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	logger := New(
+		SlogOutput(h),
+		Metadata(map[string]interface{}{"service": "book"}),
+		nowFunc(func() time.Time { return time.Date(2019, 10, 22, 0, 0, 0, 0, time.UTC) }),
+		withoutTrace(true),
+	)
+
+	logger.Debug("debug")
+	assert.Empty(buf.String())
+
+	logger.Infow("info", "request_id", "req1")
+	assert.Equal(`{"time":"2019-10-22T00:00:00Z","level":"INFO","msg":"info","request_id":"req1","meta":{"service":"book"}}
+`, buf.String())
+
+	buf.Reset()
+	logger.SetFlattenMetadata(true)
+	logger.Error(errors.New("error"))
+	assert.Equal(`{"time":"2019-10-22T00:00:00Z","level":"ERROR","msg":"","error":[{"message":"error","type":"*errors.errorString"}],"service":"book"}
+`, buf.String())
+}
@@ -2,73 +2,258 @@ package log
 
 import (
 	"fmt"
+	"path"
 	"runtime"
-)
+	"strings"
+	"sync"
 
-type frame uintptr
+	"github.com/kyfk/log/level"
+	"github.com/pkg/errors"
+)
 
-// pc returns the program counter for this frame;
-// multiple frames may have the same PC value.
-func (f frame) pc() uintptr { return uintptr(f) - 1 }
+// Frame is a location of source code.
+type Frame struct {
+	Function string `json:"func"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
 
-// file returns the full path to the file that contains the
-// function for this frame's pc.
-func (f frame) file() string {
-	fn := runtime.FuncForPC(f.pc())
-	if fn == nil {
+// String returns the frame in the same form as the frames of the stack trace.
+func (f Frame) String() string {
+	if f.Function == "" {
 		return "unknown"
 	}
-	file, _ := fn.FileLine(f.pc())
-	return file
+	return fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line)
+}
+
+// defaultTraceDepth is the default maximum number of frames of stack traces.
+const defaultTraceDepth = 32
+
+// TraceConfig is the configuration of the stack traces that the logger captures.
+// The zero value captures up to 32 frames as strings at Warn and the higher levels.
+type TraceConfig struct {
+	// MaxDepth is the maximum number of frames. If it isn't positive, 32 is used.
+	MaxDepth int
+	// SkipRuntime skips the frames of the runtime package like runtime.main and runtime.goexit.
+	SkipRuntime bool
+	// SkipLibrary skips the frames of this package in the middle of the stack,
+	// like the ones of Fatal calling the exit hooks.
+	// The frames above the caller of the logger are always skipped.
+	SkipLibrary bool
+	// TrimPath trims the file paths to the form of the import path of the package
+	// like "github.com/kyfk/log/logger.go" as -trimpath does.
+	TrimPath bool
+	// Structured outputs the frames as Frame objects instead of strings.
+	Structured bool
+	// Levels are the levels at which the stack traces are captured.
+	// If it is nil, they are captured at Warn and the higher levels,
+	// and if it is empty but not nil, they aren't captured at all.
+	Levels []level.Level
 }
 
-// line returns the line number of source code of the
-// function for this frame's pc.
-func (f frame) line() int {
-	fn := runtime.FuncForPC(f.pc())
-	if fn == nil {
-		return 0
+// captures reports whether the stack trace is captured at lv.
+func (c *config) captures(lv level.Level) bool {
+	if c.withoutTrace {
+		return false
 	}
-	_, line := fn.FileLine(f.pc())
-	return line
+	if c.trace.Levels == nil {
+		return !lv.LessThan(level.Warn)
+	}
+	for _, l := range c.trace.Levels {
+		if l == lv {
+			return true
+		}
+	}
+	return false
 }
 
-// name returns the name of this function if known.
-func (f frame) name() string {
-	fn := runtime.FuncForPC(f.pc())
-	if fn == nil {
-		return "unknown"
+// captureTrace returns the stack trace from the frame at pc.
+// If pc is 0 or isn't found in the stack, the stack trace is from the caller of the logger.
+func (c *config) captureTrace(pc uintptr) interface{} {
+	pcs := c.capturePCs(pc)
+	if pcs == nil {
+		return nil
+	}
+	return c.renderTrace(pcs)
+}
+
+// capturePCs returns the program counters of the stack from the frame at pc.
+// If pc is 0 or isn't found in the stack, they are from the caller of the logger
+// skipping the frames set by CallerSkip.
+func (c *config) capturePCs(pc uintptr) []uintptr {
+	pcs := make([]uintptr, c.traceDepth()+c.callerSkip+32)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+
+	start := -1
+	for i, p := range pcs {
+		if p == pc {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		for i, p := range pcs {
+			if !frameOf(p).library {
+				start = i + c.callerSkip
+				break
+			}
+		}
 	}
-	return fn.Name()
+	if start < 0 || start >= len(pcs) {
+		return nil
+	}
+	return pcs[start:]
 }
 
-func (f frame) marshalText() string {
-	name := f.name()
-	if name == "unknown" {
-		return name
+func (c *config) traceDepth() int {
+	if c.trace.MaxDepth <= 0 {
+		return defaultTraceDepth
 	}
-	return fmt.Sprintf("%s %s:%d", name, f.file(), f.line())
+	return c.trace.MaxDepth
 }
 
-type stackTrace []frame
+// renderTrace renders the frames at pcs along the configuration.
+// All the stack traces, captured by the logger or extracted from errors, are rendered by it
+// so that they have the same form.
+func (c *config) renderTrace(pcs []uintptr) interface{} {
+	depth := c.traceDepth()
+	frames := make([]Frame, 0, len(pcs))
+	for _, pc := range pcs {
+		if len(frames) == depth {
+			break
+		}
+		f := frameOf(pc)
+		if c.trace.SkipRuntime && f.pkg == "runtime" || c.trace.SkipLibrary && f.library {
+			continue
+		}
+		file := f.frame.File
+		if c.trace.TrimPath {
+			file = trimPath(file, f.pkg)
+		}
+		frames = append(frames, Frame{Function: f.frame.Function, File: file, Line: f.frame.Line})
+	}
 
-func (s stackTrace) framesString() []string {
-	arr := make([]string, len(s))
-	for i, ss := range s {
-		arr[i] = ss.marshalText()
+	if c.trace.Structured {
+		return frames
+	}
+	arr := make([]string, len(frames))
+	for i, f := range frames {
+		arr[i] = f.String()
 	}
 	return arr
 }
 
-func callers() stackTrace {
-	const depth = 32
-	var pcs [depth]uintptr
-	n := runtime.Callers(3, pcs[:])
-	st := pcs[0:n]
+// trimPath trims file to the import path of the package pkg and the file name.
+// The files of the standard library are trimmed to the path under GOROOT/src,
+// and the files of the main package are trimmed to the last directory and the file name.
+func trimPath(file, pkg string) string {
+	if root := runtime.GOROOT(); root != "" && strings.HasPrefix(file, root+"/src/") {
+		return strings.TrimPrefix(file, root+"/src/")
+	}
+	if pkg == "" || pkg == "main" {
+		return shortPath(file)
+	}
+	return pkg + "/" + path.Base(file)
+}
+
+// StackTracer extracts the stack trace from an error of an error library.
+// Each error in the chain is passed, so it doesn't need to unwrap err.
+// The stack trace is returned as the program counters in the form of runtime.Callers,
+// and the second returned value reports whether err has the stack trace.
+//
+// The errors of github.com/pkg/errors and the ones that have the same StackTrace method
+// are supported without StackTracer.
+type StackTracer interface {
+	StackTrace(err error) ([]uintptr, bool)
+}
+
+// StackTracerFunc is an adapter to allow the use of ordinary functions as StackTracer.
+type StackTracerFunc func(err error) ([]uintptr, bool)
+
+// StackTrace calls f(err).
+func (f StackTracerFunc) StackTrace(err error) ([]uintptr, bool) {
+	return f(err)
+}
+
+type namedStackTracer struct {
+	name   string
+	tracer StackTracer
+}
+
+var stackTracers = struct {
+	sync.RWMutex
+	s []namedStackTracer
+}{}
+
+// RegisterStackTracer registers StackTracer with name so that the stack traces of the errors
+// of other error libraries are output in the same form as the others.
+// The tracers are tried in the order of registration.
+// If a tracer is already registered with name, it is replaced.
+func RegisterStackTracer(name string, t StackTracer) {
+	stackTracers.Lock()
+	defer stackTracers.Unlock()
+	for i := range stackTracers.s {
+		if stackTracers.s[i].name == name {
+			stackTracers.s[i].tracer = t
+			return
+		}
+	}
+	stackTracers.s = append(stackTracers.s, namedStackTracer{name: name, tracer: t})
+}
+
+// UnregisterStackTracer removes StackTracer registered with name.
+func UnregisterStackTracer(name string) {
+	stackTracers.Lock()
+	defer stackTracers.Unlock()
+	for i := range stackTracers.s {
+		if stackTracers.s[i].name == name {
+			stackTracers.s = append(stackTracers.s[:i:i], stackTracers.s[i+1:]...)
+			return
+		}
+	}
+}
+
+// stackTraceOfError returns the stack trace of err itself.
+func stackTraceOfError(err error) ([]uintptr, bool) {
+	if e, ok := err.(interface{ StackTrace() errors.StackTrace }); ok {
+		st := e.StackTrace()
+		pcs := make([]uintptr, len(st))
+		for i, f := range st {
+			pcs[i] = uintptr(f)
+		}
+		return pcs, true
+	}
+
+	stackTracers.RLock()
+	defer stackTracers.RUnlock()
+	for _, t := range stackTracers.s {
+		if pcs, ok := t.tracer.StackTrace(err); ok {
+			return pcs, true
+		}
+	}
+	return nil, false
+}
+
+// stackError is an error that has the stack trace where it is created.
+// It isn't output as a layer of the error chain.
+type stackError struct {
+	error
+	stack []uintptr
+}
+
+// Unwrap returns the error built by fmt.Errorf if it wraps any errors.
+func (e *stackError) Unwrap() error {
+	if unwrap(e.error) == nil && unwrapMulti(e.error) == nil {
+		return nil
+	}
+	return e.error
+}
 
-	f := make([]frame, len(st))
-	for i := 0; i < len(f); i++ {
-		f[i] = frame((st)[i])
+// StackTrace returns the stack trace in the form of github.com/pkg/errors.
+func (e *stackError) StackTrace() errors.StackTrace {
+	st := make(errors.StackTrace, len(e.stack))
+	for i, pc := range e.stack {
+		st[i] = errors.Frame(pc)
 	}
-	return f
+	return st
 }
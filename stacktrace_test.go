@@ -0,0 +1,177 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kyfk/log/format"
+	"github.com/kyfk/log/level"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func traceOfOutput(t *testing.T, buf *bytes.Buffer) []interface{} {
+	var v struct {
+		Trace []interface{} `json:"trace"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &v))
+	buf.Reset()
+	return v.Trace
+}
+
+func hasFrame(trace []interface{}, prefix string) bool {
+	for _, f := range trace {
+		if s, ok := f.(string); ok && strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStackTraces(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(Format(format.JSON), Output(buf))
+
+	t.Run("by default, captured at Warn and the higher levels from the caller", func(t *testing.T) {
+		logger.Info("info")
+		assert.Nil(traceOfOutput(t, buf))
+
+		logger.Warn("warn")
+		trace := traceOfOutput(t, buf)
+		assert.True(strings.HasPrefix(trace[0].(string), "github.com/kyfk/log.TestStackTraces.func"), trace[0])
+		assert.True(hasFrame(trace, "runtime.goexit"))
+	})
+
+	t.Run("the package-level functions are skipped", func(t *testing.T) {
+		SetFormat(format.JSON)
+		SetOutput(buf)
+		Warn("warn")
+		trace := traceOfOutput(t, buf)
+		assert.True(strings.HasPrefix(trace[0].(string), "github.com/kyfk/log.TestStackTraces.func"), trace[0])
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		logger.SetStackTraces(TraceConfig{MaxDepth: 2})
+		logger.Warn("warn")
+		assert.Len(traceOfOutput(t, buf), 2)
+	})
+
+	t.Run("skip runtime", func(t *testing.T) {
+		logger.SetStackTraces(TraceConfig{SkipRuntime: true})
+		logger.Warn("warn")
+		assert.False(hasFrame(traceOfOutput(t, buf), "runtime."))
+	})
+
+	t.Run("skip library", func(t *testing.T) {
+		// the frames of Fatal are in the middle of the stack of the exit hook.
+		hookBuf := bytes.NewBuffer(nil)
+		hookLogger := New(Format(format.JSON), Output(hookBuf))
+		logger.SetExitFunc(func(int) {})
+		logger.AddExitHooks(func() { hookLogger.Warn("exiting") })
+		defer logger.update(ExitHooks())
+
+		logger.Fatal("fatal")
+		assert.True(hasFrame(traceOfOutput(t, hookBuf), "github.com/kyfk/log.(*Logger).Fatal"))
+
+		hookLogger.SetStackTraces(TraceConfig{SkipLibrary: true})
+		logger.Fatal("fatal")
+		assert.False(hasFrame(traceOfOutput(t, hookBuf), "github.com/kyfk/log.(*Logger)"))
+		buf.Reset()
+	})
+
+	t.Run("structured frames with trimmed paths", func(t *testing.T) {
+		logger.SetStackTraces(TraceConfig{Structured: true, TrimPath: true})
+		logger.Warn("warn")
+		frame := traceOfOutput(t, buf)[0].(map[string]interface{})
+		assert.Equal("github.com/kyfk/log/stacktrace_test.go", frame["file"])
+		assert.Contains(frame["func"], "TestStackTraces")
+		assert.NotZero(frame["line"])
+	})
+
+	t.Run("choose levels", func(t *testing.T) {
+		logger.SetStackTraces(TraceConfig{Levels: []level.Level{level.Info}})
+		logger.Info("info")
+		assert.NotEmpty(traceOfOutput(t, buf))
+		logger.Warn("warn")
+		assert.Nil(traceOfOutput(t, buf))
+
+		logger.SetStackTraces(TraceConfig{Levels: []level.Level{}})
+		logger.Error(errors.New("error"))
+		assert.Nil(traceOfOutput(t, buf))
+	})
+}
+
+func TestTrimPath(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("runtime/proc.go", trimPath(runtime.GOROOT()+"/src/runtime/proc.go", "runtime"))
+	assert.Equal("github.com/kyfk/log/logger.go", trimPath("/home/user/log/logger.go", "github.com/kyfk/log"))
+	assert.Equal("app/main.go", trimPath("/home/user/app/main.go", "main"))
+}
+
+// callersError is an error of another error library that has the stack trace.
+type callersError struct {
+	msg     string
+	callers []uintptr
+}
+
+func newCallersError(msg string) error {
+	pcs := make([]uintptr, 32)
+	return &callersError{msg: msg, callers: pcs[:runtime.Callers(2, pcs)]}
+}
+
+func (e *callersError) Error() string { return e.msg }
+
+func TestStackTracesOfErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	logger := New(Format(format.JSON), Output(buf))
+
+	t.Run("the trace of pkg/errors has the same form as the captured one", func(t *testing.T) {
+		err := errors.New("error")
+		logger.Error(err)
+		fromError := traceOfOutput(t, buf)
+		logger.Warn("warn")
+		captured := traceOfOutput(t, buf)
+
+		assert.True(strings.HasPrefix(fromError[0].(string), "github.com/kyfk/log.TestStackTracesOfErrors.func"), fromError[0])
+		frameForm := regexp.MustCompile(`^\S+ \S+:\d+$`)
+		for _, f := range append(fromError, captured...) {
+			assert.Regexp(frameForm, f)
+		}
+
+		logger.SetStackTraces(TraceConfig{Structured: true, MaxDepth: 1})
+		defer logger.SetStackTraces(TraceConfig{})
+		logger.Error(err)
+		trace := traceOfOutput(t, buf)
+		assert.Len(trace, 1)
+		assert.Contains(trace[0].(map[string]interface{})["func"], "TestStackTracesOfErrors")
+	})
+
+	t.Run("registered StackTracer", func(t *testing.T) {
+		RegisterStackTracer("callers", StackTracerFunc(func(err error) ([]uintptr, bool) {
+			if e, ok := err.(*callersError); ok {
+				return e.callers, true
+			}
+			return nil, false
+		}))
+		defer UnregisterStackTracer("callers")
+
+		err := newCallersError("error")
+		logger.Error(fmt.Errorf("wrapped: %w", err))
+		trace := traceOfOutput(t, buf)
+		assert.True(strings.HasPrefix(trace[0].(string), "github.com/kyfk/log.TestStackTracesOfErrors.func"), trace[0])
+
+		UnregisterStackTracer("callers")
+		_, ok := stackTraceOf(err)
+		assert.False(ok)
+	})
+}